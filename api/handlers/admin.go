@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+)
+
+func (h *Handler) ListUsers(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.UserListing)
+	if !ok {
+		return
+	}
+
+	users, page, err := h.app.Admin.ListUsers(ctx.Request.Context(), q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Users retrieved"
+	responseBody.Status = true
+	responseBody.Data = users
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetUser(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid user id")
+	if !ok {
+		return
+	}
+
+	details, err := h.app.Admin.GetUser(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "User retrieved"
+	responseBody.Status = true
+	responseBody.Data = details
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ListUserTransactions(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid user id")
+	if !ok {
+		return
+	}
+
+	q, ok := listQuery(ctx, models.TransactionListing)
+	if !ok {
+		return
+	}
+
+	transactions, page, err := h.app.Admin.ListUserTransactions(ctx.Request.Context(), id, q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Transactions retrieved"
+	responseBody.Status = true
+	responseBody.Data = transactions
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ListAuditLog(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.AuditListing)
+	if !ok {
+		return
+	}
+
+	entries, page, err := h.app.Admin.AuditLog(ctx.Request.Context(), q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Audit log retrieved"
+	responseBody.Status = true
+	responseBody.Data = entries
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) SuspendUser(ctx *gin.Context) {
+	h.changeUser(ctx, "User suspended", h.app.Admin.Suspend)
+}
+
+func (h *Handler) ReactivateUser(ctx *gin.Context) {
+	h.changeUser(ctx, "User reactivated", h.app.Admin.Reactivate)
+}
+
+func (h *Handler) ForcePasswordReset(ctx *gin.Context) {
+	h.changeUser(ctx, "Password reset required", h.app.Admin.ForcePasswordReset)
+}
+
+func (h *Handler) DeleteUser(ctx *gin.Context) {
+	h.changeUser(ctx, "User deleted", h.app.Admin.Delete)
+}
+
+func (h *Handler) ChangeUserType(ctx *gin.Context) {
+	var req models.ChangeUserTypeRequest
+
+	h.changeUserWith(ctx, &req, "User type changed", func(v *validator.Validator) {
+		models.ValidateChangeUserType(v, &req)
+	}, func(c context.Context, actor *models.User, id int64) (*models.User, error) {
+		return h.app.Admin.ChangeUserType(c, actor, id, req.UserType, req.Reason)
+	})
+}
+
+// changeUser runs an admin action on the user in the path that only takes
+// the reason for it.
+func (h *Handler) changeUser(ctx *gin.Context, message string, action func(context.Context, *models.User, int64, string) (*models.User, error)) {
+	var req models.AdminActionRequest
+
+	h.changeUserWith(ctx, &req, message, func(v *validator.Validator) {
+		models.ValidateAuditReason(v, req.Reason)
+	}, func(c context.Context, actor *models.User, id int64) (*models.User, error) {
+		return action(c, actor, id, req.Reason)
+	})
+}
+
+// changeUserWith binds the body into req, validates it and runs the action
+// on the user in the path as the signed in admin.
+func (h *Handler) changeUserWith(ctx *gin.Context, req any, message string, validate func(*validator.Validator), action func(context.Context, *models.User, int64) (*models.User, error)) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid user id")
+	if !ok {
+		return
+	}
+
+	err := ctx.ShouldBindJSON(req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if validate(v); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	user, err := action(ctx.Request.Context(), middleware.CurrentUser(ctx), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = message
+	responseBody.Status = true
+	responseBody.Data = user
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *Handler) Login(ctx *gin.Context) {
+	var user models.User
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&user)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	models.ValidateEmail(v, user.Email)
+	if v.Check(user.Password != "", "password", "must be provided"); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	_, tokens, err := h.app.Auth.Login(ctx.Request.Context(), user.Email, user.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Login successful"
+	responseBody.Status = true
+	responseBody.Data = tokens
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) RefreshToken(ctx *gin.Context) {
+	var body refreshTokenRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.RefreshToken == "" {
+		ctx.Error(models.InvalidRequest("Refresh token not provided", err))
+		return
+	}
+
+	_, tokens, err := h.app.Auth.Refresh(ctx.Request.Context(), body.RefreshToken)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Token refreshed"
+	responseBody.Status = true
+	responseBody.Data = tokens
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) Logout(ctx *gin.Context) {
+	var body refreshTokenRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.RefreshToken == "" {
+		ctx.Error(models.InvalidRequest("Refresh token not provided", err))
+		return
+	}
+
+	err = h.app.Auth.Logout(ctx.Request.Context(), body.RefreshToken)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Logged out"
+	responseBody.Status = true
+	ctx.JSON(http.StatusOK, responseBody)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+)
+
+func (h *Handler) CreateFeedback(ctx *gin.Context) {
+	var feedback models.Feedback
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid transaction id")
+	if !ok {
+		return
+	}
+
+	feedback, ok = bindFeedback(ctx)
+	if !ok {
+		return
+	}
+	feedback.TransactionID = id
+	feedback.UserID = middleware.CurrentUser(ctx).Id
+
+	err := h.app.Feedback.Create(ctx.Request.Context(), &feedback)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Feedback saved"
+	responseBody.Status = true
+	responseBody.Data = feedback
+
+	ctx.JSON(http.StatusCreated, responseBody)
+}
+
+func (h *Handler) UpdateFeedback(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	feedback, ok := h.loadOwnFeedback(ctx)
+	if !ok {
+		return
+	}
+
+	update, ok := bindFeedback(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.app.Feedback.Update(ctx.Request.Context(), feedback, update.Rating, update.Comment)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Feedback updated"
+	responseBody.Status = true
+	responseBody.Data = feedback
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) DeleteFeedback(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	feedback, ok := h.loadOwnFeedback(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.app.Feedback.Delete(ctx.Request.Context(), feedback)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Feedback deleted"
+	responseBody.Status = true
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// RemoveFeedback lets an admin moderate any feedback.
+func (h *Handler) RemoveFeedback(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid feedback id")
+	if !ok {
+		return
+	}
+
+	feedback, err := h.app.Feedback.GetByID(ctx.Request.Context(), id)
+	if err == nil {
+		err = h.app.Feedback.Delete(ctx.Request.Context(), feedback)
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Feedback deleted"
+	responseBody.Status = true
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetPublicPartner(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid partner id")
+	if !ok {
+		return
+	}
+
+	partner, err := h.app.Partners.PublicProfile(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partner retrieved"
+	responseBody.Status = true
+	responseBody.Data = partner
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ListPartnerFeedback(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid partner id")
+	if !ok {
+		return
+	}
+
+	q, ok := listQuery(ctx, models.FeedbackListing)
+	if !ok {
+		return
+	}
+
+	feedback, page, err := h.app.Feedback.ListForPartner(ctx.Request.Context(), id, q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Feedback retrieved"
+	responseBody.Status = true
+	responseBody.Data = feedback
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func bindFeedback(ctx *gin.Context) (models.Feedback, bool) {
+	var feedback models.Feedback
+
+	err := ctx.ShouldBindJSON(&feedback)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return feedback, false
+	}
+
+	v := validator.New()
+
+	if models.ValidateFeedback(v, &feedback); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return feedback, false
+	}
+
+	return feedback, true
+}
+
+func (h *Handler) loadOwnFeedback(ctx *gin.Context) (*models.Feedback, bool) {
+	id, ok := paramID(ctx, "id", "Invalid transaction id")
+	if !ok {
+		return nil, false
+	}
+
+	feedback, err := h.app.Feedback.GetForUserTransaction(ctx.Request.Context(), middleware.CurrentUser(ctx).Id, id)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return feedback, true
+}
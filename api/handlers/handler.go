@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/app"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+)
+
+// Handler serves the API endpoints. Every dependency comes from the
+// container rather than package globals.
+type Handler struct {
+	app *app.Container
+}
+
+func New(container *app.Container) *Handler {
+	return &Handler{app: container}
+}
+
+// listQuery parses the paging, sort and filter parameters of a list request
+// against spec, failing the request when they are invalid.
+func listQuery[T any](ctx *gin.Context, spec listing.Spec[T]) (listing.Query, bool) {
+	v := validator.New()
+
+	q := listing.Parse(v, ctx.Request.URL.Query(), spec)
+	if !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return q, false
+	}
+
+	return q, true
+}
+
+func pagination(q listing.Query, page listing.Page) *response.Pagination {
+	return &response.Pagination{
+		Limit:      q.Limit,
+		Offset:     q.Offset,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/buildinfo"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"net/http"
+)
+
+// Health reports that the process is up and serving requests. It does not
+// look at any dependency, so a failing database never gets the process
+// restarted.
+func (h *Handler) Health(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	responseBody.Error = false
+	responseBody.Message = "Alive"
+	responseBody.Status = true
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// Ready tells load balancers whether to route traffic to this instance. It
+// runs every readiness check and reports unavailable when one fails, until
+// startup completes and as soon as shutdown begins. The probe is public, so
+// why a check failed is only logged.
+func (h *Handler) Ready(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	checks, ready := h.app.Lifecycle.Check(ctx.Request.Context())
+	for _, check := range checks {
+		if check.Err != nil {
+			logging.FromContext(ctx.Request.Context()).Warn("readiness check failed",
+				"check", check.Name,
+				"latency", check.Latency,
+				"error", check.Err,
+			)
+		}
+	}
+	responseBody.Data = gin.H{"checks": checks}
+
+	if !ready {
+		responseBody.Error = true
+		responseBody.RequestID = middleware.CurrentRequestID(ctx)
+		responseBody.Message = "Not ready"
+		responseBody.Status = false
+		ctx.JSON(http.StatusServiceUnavailable, responseBody)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Ready"
+	responseBody.Status = true
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// Version reports the commit and build time of the running binary.
+func (h *Handler) Version(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	responseBody.Error = false
+	responseBody.Message = "Build information"
+	responseBody.Status = true
+	responseBody.Data = buildinfo.Get()
+	ctx.JSON(http.StatusOK, responseBody)
+}
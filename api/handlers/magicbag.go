@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) CreateMagicBag(ctx *gin.Context) {
+	var bag models.MagicBag
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&bag)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateMagicBag(v, &bag); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	bag.PartnerID = middleware.CurrentPartner(ctx).ID
+	err = h.app.MagicBags.Create(ctx.Request.Context(), &bag)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag created"
+	responseBody.Status = true
+	responseBody.Data = bag
+
+	ctx.JSON(http.StatusCreated, responseBody)
+}
+
+// UpdateMagicBag changes the fields the request sends and keeps the others.
+func (h *Handler) UpdateMagicBag(ctx *gin.Context) {
+	var changes models.MagicBagChanges
+	var responseBody response.JsonResponse
+
+	existing, ok := h.loadOwnMagicBag(ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.ShouldBindJSON(&changes)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	bag := *existing
+	changes.Apply(&bag)
+
+	v := validator.New()
+
+	if models.ValidateMagicBag(v, &bag); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	err = h.app.MagicBags.Update(ctx.Request.Context(), &bag, &changes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	updated, err := h.app.MagicBags.GetForPartner(ctx.Request.Context(), bag.PartnerID, bag.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag updated"
+	responseBody.Status = true
+	responseBody.Data = updated
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) WithdrawMagicBag(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	bag, ok := h.loadOwnMagicBag(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.app.MagicBags.Withdraw(ctx.Request.Context(), bag)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag withdrawn"
+	responseBody.Status = true
+	responseBody.Data = bag
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetOwnMagicBag(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	bag, ok := h.loadOwnMagicBag(ctx)
+	if !ok {
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag retrieved"
+	responseBody.Status = true
+	responseBody.Data = bag
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ListOwnMagicBags(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.MagicBagListing)
+	if !ok {
+		return
+	}
+
+	bags, page, err := h.app.MagicBags.ListForPartner(ctx.Request.Context(), middleware.CurrentPartner(ctx).ID, q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bags retrieved"
+	responseBody.Status = true
+	responseBody.Data = bags
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// ListMagicBags is the waste warrior view of bags on sale. It never includes
+// the bag contents.
+func (h *Handler) ListMagicBags(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.MagicBagListing)
+	if !ok {
+		return
+	}
+
+	bags, page, err := h.app.MagicBags.ListAvailable(ctx.Request.Context(), q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bags retrieved"
+	responseBody.Status = true
+	responseBody.Data = bags
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetMagicBag(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid magic bag id")
+	if !ok {
+		return
+	}
+
+	bag, err := h.app.MagicBags.GetAvailable(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag retrieved"
+	responseBody.Status = true
+	responseBody.Data = bag
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) loadOwnMagicBag(ctx *gin.Context) (*models.MagicBag, bool) {
+	id, ok := paramID(ctx, "id", "Invalid magic bag id")
+	if !ok {
+		return nil, false
+	}
+
+	bag, err := h.app.MagicBags.GetForPartner(ctx.Request.Context(), middleware.CurrentPartner(ctx).ID, id)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return bag, true
+}
+
+// paramID parses a positive integer path parameter, failing the request
+// when it is not one.
+func paramID(ctx *gin.Context, name, message string) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.Error(models.InvalidRequest(message, nil))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+)
+
+// partnerProfileRequest holds the profile fields partners can edit. The
+// status and review details are only changed by admins.
+type partnerProfileRequest struct {
+	BRNumber string `json:"business_number"`
+	Logo     string `json:"logo"`
+	Address  string `json:"address"`
+	Timezone string `json:"timezone"`
+}
+
+func (r partnerProfileRequest) apply(partner *models.Partner) {
+	partner.BRNumber = r.BRNumber
+	partner.Logo = r.Logo
+	partner.Address = r.Address
+	partner.Timezone = r.Timezone
+}
+
+type partnerReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) CreatePartnerProfile(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	// Partners who leave out the time zone get UTC.
+	body := partnerProfileRequest{Timezone: "UTC"}
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	var partner models.Partner
+	body.apply(&partner)
+
+	v := validator.New()
+
+	if models.ValidatePartner(v, &partner); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	partner.UserID = middleware.CurrentUser(ctx).Id
+	err = h.app.Partners.Create(ctx.Request.Context(), &partner)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partner profile submitted for review"
+	responseBody.Status = true
+	responseBody.Data = partner
+
+	ctx.JSON(http.StatusCreated, responseBody)
+}
+
+// UpdatePartnerProfile changes the fields the request sends and keeps the
+// others.
+func (h *Handler) UpdatePartnerProfile(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	existing, ok := h.loadOwnPartner(ctx)
+	if !ok {
+		return
+	}
+
+	body := partnerProfileRequest{
+		BRNumber: existing.BRNumber,
+		Logo:     existing.Logo,
+		Address:  existing.Address,
+		Timezone: existing.Timezone,
+	}
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	partner := *existing
+	body.apply(&partner)
+
+	v := validator.New()
+
+	if models.ValidatePartner(v, &partner); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	err = h.app.Partners.Update(ctx.Request.Context(), &partner, existing)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	updated, err := h.app.Partners.GetByID(ctx.Request.Context(), partner.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partner profile updated"
+	responseBody.Status = true
+	responseBody.Data = updated
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetPartnerProfile(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	partner, ok := h.loadOwnPartner(ctx)
+	if !ok {
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partner profile retrieved"
+	responseBody.Status = true
+	responseBody.Data = partner
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ListPartners(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.PartnerListing)
+	if !ok {
+		return
+	}
+
+	partners, page, err := h.app.Partners.List(ctx.Request.Context(), q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partners retrieved"
+	responseBody.Status = true
+	responseBody.Data = partners
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ApprovePartner(ctx *gin.Context) {
+	h.reviewPartner(ctx, models.PartnerApproved)
+}
+
+func (h *Handler) RejectPartner(ctx *gin.Context) {
+	h.reviewPartner(ctx, models.PartnerRejected)
+}
+
+func (h *Handler) reviewPartner(ctx *gin.Context, status models.PartnerStatus) {
+	var body partnerReviewRequest
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid partner id")
+	if !ok {
+		return
+	}
+
+	// The reason is optional when approving, so an empty body is fine.
+	_ = ctx.ShouldBindJSON(&body)
+
+	v := validator.New()
+	if status == models.PartnerRejected {
+		v.Check(body.Reason != "", "reason", "must be provided")
+	}
+	if v.Check(len(body.Reason) <= 255, "reason", "must not be more than 255 bytes long"); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	partner, err := h.app.Partners.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = h.app.Partners.Review(ctx.Request.Context(), partner, status, body.Reason)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Partner profile " + string(status)
+	responseBody.Status = true
+	responseBody.Data = partner
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) loadOwnPartner(ctx *gin.Context) (*models.Partner, bool) {
+	partner, err := h.app.Partners.GetByUserID(ctx.Request.Context(), middleware.CurrentUser(ctx).Id)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return partner, true
+}
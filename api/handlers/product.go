@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/http"
+)
+
+// ListProducts is the public product catalog: the shared products and those
+// of approved partners.
+func (h *Handler) ListProducts(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.ProductListing)
+	if !ok {
+		return
+	}
+
+	products, page, err := h.app.Products.List(ctx.Request.Context(), q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Products retrieved"
+	responseBody.Status = true
+	responseBody.Data = products
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetProduct(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid product id")
+	if !ok {
+		return
+	}
+
+	product, err := h.app.Products.GetPublic(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Product retrieved"
+	responseBody.Status = true
+	responseBody.Data = product
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// ListUsableProducts lists the products the partner can put in their bags.
+func (h *Handler) ListUsableProducts(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.ProductListing)
+	if !ok {
+		return
+	}
+
+	products, page, err := h.app.Products.ListUsable(ctx.Request.Context(), middleware.CurrentPartner(ctx).ID, q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Products retrieved"
+	responseBody.Status = true
+	responseBody.Data = products
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) CreatePartnerProduct(ctx *gin.Context) {
+	partnerId := middleware.CurrentPartner(ctx).ID
+	h.createProduct(ctx, &partnerId)
+}
+
+func (h *Handler) UpdatePartnerProduct(ctx *gin.Context) {
+	product, ok := h.loadOwnProduct(ctx)
+	if !ok {
+		return
+	}
+	h.updateProduct(ctx, product)
+}
+
+func (h *Handler) DeletePartnerProduct(ctx *gin.Context) {
+	product, ok := h.loadOwnProduct(ctx)
+	if !ok {
+		return
+	}
+	h.deleteProduct(ctx, product)
+}
+
+// CreateProduct adds a product to the shared catalog.
+func (h *Handler) CreateProduct(ctx *gin.Context) {
+	h.createProduct(ctx, nil)
+}
+
+func (h *Handler) UpdateProduct(ctx *gin.Context) {
+	product, ok := h.loadProduct(ctx)
+	if !ok {
+		return
+	}
+	h.updateProduct(ctx, product)
+}
+
+func (h *Handler) DeleteProduct(ctx *gin.Context) {
+	product, ok := h.loadProduct(ctx)
+	if !ok {
+		return
+	}
+	h.deleteProduct(ctx, product)
+}
+
+func (h *Handler) createProduct(ctx *gin.Context, partnerId *int64) {
+	var product models.Product
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&product)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateProduct(v, &product); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	product.PartnerID = partnerId
+	err = h.app.Products.Create(ctx.Request.Context(), &product)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Product created"
+	responseBody.Status = true
+	responseBody.Data = product
+
+	ctx.JSON(http.StatusCreated, responseBody)
+}
+
+// updateProduct applies the fields of the body to the product. Fields the
+// client leaves out keep their values.
+func (h *Handler) updateProduct(ctx *gin.Context, existing *models.Product) {
+	var responseBody response.JsonResponse
+
+	product := *existing
+	err := ctx.ShouldBindJSON(&product)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+	product.Id = existing.Id
+	product.PartnerID = existing.PartnerID
+
+	v := validator.New()
+
+	if models.ValidateProduct(v, &product); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	err = h.app.Products.Update(ctx.Request.Context(), &product)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Product updated"
+	responseBody.Status = true
+	responseBody.Data = product
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) deleteProduct(ctx *gin.Context, product *models.Product) {
+	var responseBody response.JsonResponse
+
+	err := h.app.Products.Delete(ctx.Request.Context(), product)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Product deleted"
+	responseBody.Status = true
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) loadProduct(ctx *gin.Context) (*models.Product, bool) {
+	id, ok := paramID(ctx, "id", "Invalid product id")
+	if !ok {
+		return nil, false
+	}
+
+	product, err := h.app.Products.Get(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return product, true
+}
+
+func (h *Handler) loadOwnProduct(ctx *gin.Context) (*models.Product, bool) {
+	id, ok := paramID(ctx, "id", "Invalid product id")
+	if !ok {
+		return nil, false
+	}
+
+	product, err := h.app.Products.GetForPartner(ctx.Request.Context(), middleware.CurrentPartner(ctx).ID, id)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return product, true
+}
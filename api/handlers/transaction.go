@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"io"
+	"net/http"
+)
+
+type purchaseRequest struct {
+	PaymentType   models.PaymentType `json:"payment_type"`
+	PaymentSource string             `json:"payment_source"`
+}
+
+func (h *Handler) PurchaseMagicBag(ctx *gin.Context) {
+	var body purchaseRequest
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid magic bag id")
+	if !ok {
+		return
+	}
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidatePurchase(v, body.PaymentType, body.PaymentSource); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	transaction, err := h.app.Purchases.Purchase(ctx.Request.Context(), middleware.CurrentUser(ctx).Id, id, body.PaymentType, body.PaymentSource)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag purchased"
+	responseBody.Status = true
+	responseBody.Data = transaction
+
+	ctx.JSON(http.StatusCreated, responseBody)
+}
+
+func (h *Handler) ListTransactions(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	q, ok := listQuery(ctx, models.TransactionListing)
+	if !ok {
+		return
+	}
+
+	transactions, page, err := h.app.Purchases.ListForUser(ctx.Request.Context(), middleware.CurrentUser(ctx).Id, q)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Transactions retrieved"
+	responseBody.Status = true
+	responseBody.Data = transactions
+	responseBody.Pagination = pagination(q, page)
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) GetTransaction(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid transaction id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.app.Purchases.GetForUser(ctx.Request.Context(), middleware.CurrentUser(ctx).Id, id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Transaction retrieved"
+	responseBody.Status = true
+	responseBody.Data = transaction
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// MarkTransactionPickedUp is called by the partner when the warrior collects
+// the bag, which also takes the payment.
+func (h *Handler) MarkTransactionPickedUp(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid transaction id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.app.Purchases.MarkPickedUp(ctx.Request.Context(), middleware.CurrentPartner(ctx).ID, id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Magic bag picked up"
+	responseBody.Status = true
+	responseBody.Data = transaction
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) RefundTransaction(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	id, ok := paramID(ctx, "id", "Invalid transaction id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.app.Purchases.Refund(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Transaction refunded"
+	responseBody.Status = true
+	responseBody.Data = transaction
+
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) PaymentWebhook(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	payload, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Could not read webhook body", err))
+		return
+	}
+
+	err = h.app.Purchases.HandleWebhook(ctx.Request.Context(), payload, ctx.GetHeader("X-Payment-Signature"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Webhook processed"
+	responseBody.Status = true
+	ctx.JSON(http.StatusOK, responseBody)
+}
@@ -1,89 +1,64 @@
 package handlers
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
 	"github.com/horlathunbhosun/reducing-food-waste/models"
 	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
 	"github.com/horlathunbhosun/reducing-food-waste/validator"
 	"net/http"
-	"strconv"
 )
 
-func Signup(ctx *gin.Context) {
-	var user models.User
+func (h *Handler) Signup(ctx *gin.Context) {
+	var req models.SignupRequest
 	var responseBody response.JsonResponse
 
-	err := ctx.ShouldBindJSON(&user)
-
-	v := validator.New()
-
-	if models.ValidateUserData(v, &user); !v.Valid() {
-		//app.failedValidationResponse(w, r, v.Errors)
-		responseBody.Error = true
-		responseBody.ErrorMessage = v.Errors
-		responseBody.Status = false
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
 		return
 	}
 
-	if err != nil {
-		fmt.Println(err)
+	v := validator.New()
 
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	if models.ValidateSignup(v, &req); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
 		return
 	}
 
-	err = user.CreateToken()
+	user, err := h.app.Auth.Signup(ctx.Request.Context(), &req)
 	if err != nil {
-		fmt.Println(err)
-		responseBody.Error = true
-		responseBody.Message = "Could not send reset token. Try again"
-		responseBody.Status = false
-		responseBody.ErrorMessage = err
-		ctx.JSON(http.StatusInternalServerError, responseBody)
+		ctx.Error(err)
 		return
 	}
 	responseBody.Error = false
-	responseBody.Message = "Reset Token sent successful"
+	responseBody.Message = "Registration successful"
 	responseBody.Status = true
 	responseBody.Data = user
 
 	ctx.JSON(http.StatusCreated, responseBody)
 }
 
-func VerificationToken(ctx *gin.Context) {
-	var user models.User
+func (h *Handler) VerificationToken(ctx *gin.Context) {
+	var req models.VerifyCodeRequest
 	var responseBody response.JsonResponse
 
-	tokenStr := ctx.Param("token")
-	if tokenStr == "" {
-		responseBody.Error = true
-		responseBody.Message = "Token not provided"
-		responseBody.Status = false
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
 		return
 	}
 
-	token, err := strconv.Atoi(tokenStr)
-	if err != nil {
-		responseBody.Error = true
-		responseBody.Message = "Invalid token format"
-		responseBody.Status = false
-		responseBody.ErrorMessage = err.Error()
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	v := validator.New()
+
+	if models.ValidateVerifyCode(v, &req); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
 		return
 	}
 
-	valid, err := user.VerifyToken(token)
-	if err != nil || !valid {
-		responseBody.Error = true
-		responseBody.Message = "Could not verify token"
-		responseBody.Status = false
-		if err != nil {
-			responseBody.ErrorMessage = err.Error()
-		}
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	err = h.app.Auth.VerifyCode(ctx.Request.Context(), req.Email, req.Code)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -93,7 +68,9 @@ func VerificationToken(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, responseBody)
 }
 
-func ResetToken(ctx *gin.Context) {
+// ResetToken always answers the same way, whether or not the email has an
+// account.
+func (h *Handler) ResetToken(ctx *gin.Context) {
 	var user models.User
 	var responseBody response.JsonResponse
 
@@ -102,50 +79,152 @@ func ResetToken(ctx *gin.Context) {
 	v := validator.New()
 
 	if models.ValidateEmail(v, user.Email); !v.Valid() {
-		responseBody.Error = true
-		responseBody.ErrorMessage = v.Errors
-		responseBody.Status = false
-		ctx.JSON(http.StatusBadRequest, responseBody)
+		ctx.Error(models.ValidationFailed(v.Errors))
 		return
 	}
 
 	if err != nil {
-		fmt.Println(err)
-		ctx.JSON(http.StatusBadRequest, responseBody)
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
 		return
 	}
 
-	exists, err := user.CheckUserWithEmailExists(user.Email)
+	err = h.app.Auth.ResendVerificationCode(ctx.Request.Context(), user.Email)
 	if err != nil {
-		responseBody.Error = true
-		responseBody.Message = "Error checking user"
-		responseBody.Status = false
-		responseBody.ErrorMessage = err.Error()
-		ctx.JSON(http.StatusInternalServerError, responseBody)
+		ctx.Error(err)
 		return
 	}
-	if !exists {
-		responseBody.Error = true
-		responseBody.Message = "User with email does not exist"
-		responseBody.Status = false
-		ctx.JSON(http.StatusBadRequest, responseBody)
+	responseBody.Error = false
+	responseBody.Message = "If the email has an account awaiting verification, a new code was sent to it"
+	responseBody.Status = true
+
+	ctx.JSON(http.StatusAccepted, responseBody)
+}
+
+func (h *Handler) Me(ctx *gin.Context) {
+	var responseBody response.JsonResponse
+
+	responseBody.Error = false
+	responseBody.Message = "User retrieved"
+	responseBody.Status = true
+	responseBody.Data = middleware.CurrentUser(ctx)
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+// ForgotPassword always answers the same way, whether or not the email has an
+// account.
+func (h *Handler) ForgotPassword(ctx *gin.Context) {
+	var req models.ForgotPasswordRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateEmail(v, req.Email); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
 		return
 	}
 
-	err = user.CreateToken()
+	err = h.app.Auth.ForgotPassword(ctx.Request.Context(), req.Email)
 	if err != nil {
-		fmt.Println(err)
-		responseBody.Error = true
-		responseBody.Message = "Could not save user. Try again"
-		responseBody.Status = false
-		responseBody.ErrorMessage = err
-		ctx.JSON(http.StatusInternalServerError, responseBody)
+		ctx.Error(err)
 		return
 	}
+
 	responseBody.Error = false
-	responseBody.Message = "Registration successful"
+	responseBody.Message = "If the email has an account, a password reset code was sent to it"
 	responseBody.Status = true
-	responseBody.Data = user
+	ctx.JSON(http.StatusAccepted, responseBody)
+}
 
-	ctx.JSON(http.StatusCreated, responseBody)
+func (h *Handler) ResetPassword(ctx *gin.Context) {
+	var req models.ResetPasswordRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateResetPassword(v, &req); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	err = h.app.Auth.ResetPassword(ctx.Request.Context(), req.Email, req.Code, req.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Password reset"
+	responseBody.Status = true
+	ctx.JSON(http.StatusOK, responseBody)
+}
+
+func (h *Handler) ChangeEmail(ctx *gin.Context) {
+	var req models.ChangeEmailRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateChangeEmail(v, &req); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	err = h.app.Auth.RequestEmailChange(ctx.Request.Context(), middleware.CurrentUser(ctx), req.NewEmail, req.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "A code to confirm the new email was sent to it"
+	responseBody.Status = true
+	ctx.JSON(http.StatusAccepted, responseBody)
+}
+
+func (h *Handler) ConfirmEmailChange(ctx *gin.Context) {
+	var req models.ConfirmEmailChangeRequest
+	var responseBody response.JsonResponse
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(models.InvalidRequest("Invalid request body", err))
+		return
+	}
+
+	v := validator.New()
+
+	if models.ValidateCode(v, req.Code); !v.Valid() {
+		ctx.Error(models.ValidationFailed(v.Errors))
+		return
+	}
+
+	user, err := h.app.Auth.ConfirmEmailChange(ctx.Request.Context(), middleware.CurrentUser(ctx), req.Code)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	responseBody.Error = false
+	responseBody.Message = "Email changed"
+	responseBody.Status = true
+	responseBody.Data = user
+	ctx.JSON(http.StatusOK, responseBody)
 }
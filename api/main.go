@@ -1,18 +1,187 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/app"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
 	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/memory"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/sqlstore"
 	"github.com/horlathunbhosun/reducing-food-waste/routes"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 )
 
 func main() {
-	database.InitDB()
-	server := gin.Default()
-	routes.RegisterRoutes(server)
-	err := server.Run(":9090")
+	// Until the configuration is loaded, log with the default settings.
+	logger, _ := logging.New(os.Stdout, "info", "json")
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal(logger, "could not load configuration", err)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		fatal(logger, "could not load configuration", err)
+	}
+
+	logger, err = logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal(slog.Default(), "could not create logger", err)
+	}
+	// Libraries that log through the standard log package end up in the
+	// same structured, redacted output.
+	slog.SetDefault(logger)
+
+	sender, err := newMailSender(cfg.Mail)
+	if err != nil {
+		fatal(logger, "could not create mail backend", err)
+	}
+
+	payments, err := newPaymentProvider(cfg.Payment)
+	if err != nil {
+		fatal(logger, "could not create payment provider", err)
+	}
+
+	var container *app.Container
+	if cfg.Database.Driver == "memory" {
+		// Nothing survives a restart, so emails skip the outbox and go
+		// straight to the mail backend.
+		container = app.New(cfg, logger, memory.New(), sender, payments)
+	} else {
+		db, err := database.Open(cfg.Database)
+		if err != nil {
+			fatal(logger, "could not connect to the database", err)
+		}
+
+		err = database.Migrate(db)
+		if err != nil {
+			fatal(logger, "could not migrate the database", err)
+		}
+
+		outbox := mailer.NewOutbox(db, sender, logger, mailer.OutboxConfig{
+			Workers:     cfg.Mail.OutboxWorkers,
+			MaxAttempts: cfg.Mail.OutboxMaxAttempts,
+		})
+		outbox.Start()
+
+		container = app.New(cfg, logger, sqlstore.New(db), outbox, payments)
+		container.Lifecycle.OnShutdown("database", func(context.Context) error {
+			return db.Close()
+		})
+		container.Lifecycle.OnShutdown("email outbox", outbox.Shutdown)
+
+		container.Metrics.RegisterDB(cfg.Database.Driver, db.DB)
+		container.Metrics.RegisterOutbox(outbox)
+
+		container.Lifecycle.AddCheck("database", db.PingContext)
+		container.Lifecycle.AddCheck("migrations", func(ctx context.Context) error {
+			pending, err := database.PendingMigrations(ctx, db)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations not applied", pending)
+			}
+			return nil
+		})
+	}
+	container.Lifecycle.AddCheck("mail", func(ctx context.Context) error {
+		return mailer.Ping(ctx, container.Mailer)
+	})
+
+	engine := gin.New()
+	routes.RegisterRoutes(engine, container)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           engine,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+	container.Lifecycle.OnShutdown("http server", server.Shutdown)
+
+	logger.Info("listening", "addr", cfg.Server.Addr, "env", cfg.Env, "database", cfg.Database.Driver, "mail", cfg.Mail.Backend, "payment", cfg.Payment.Provider)
+	err = serve(server, container.Lifecycle, logger, cfg.Server)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "server stopped with an error", err)
+	}
+	logger.Info("stopped")
+}
+
+// fatal logs an error that keeps the API from running and exits.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// serve runs the HTTP server until SIGINT or SIGTERM and then drains it: the
+// API reports not ready, keeps serving for DrainDelay so load balancers stop
+// routing to it, and then stops the server, the background workers and the
+// database in that order within ShutdownTimeout.
+func serve(server *http.Server, lifecycle *app.Lifecycle, logger *slog.Logger, cfg config.ServerConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	lifecycle.SetReady(true)
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// The server could not start; the workers and the database still
+		// need stopping.
+	case <-ctx.Done():
+		// A second signal kills the process without waiting for the drain.
+		stop()
+		logger.Info("shutting down", "drain_delay", cfg.DrainDelay)
+		lifecycle.SetReady(false)
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	return errors.Join(err, lifecycle.Shutdown(shutdownCtx))
+}
+
+// newMailSender picks the mail backend named by MAIL_BACKEND: "smtp" (the
+// default), "file" to write a maildir under MAIL_DIR, or "capture" to keep
+// emails in memory.
+func newMailSender(cfg config.MailConfig) (mailer.Sender, error) {
+	switch cfg.Backend {
+	case "smtp":
+		return mailer.New(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Sender), nil
+	case "file":
+		return mailer.NewFileSender(cfg.Dir, cfg.Sender)
+	case "capture":
+		return mailer.NewCaptureSender(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q", cfg.Backend)
+	}
+}
+
+// newPaymentProvider picks the gateway named by PAYMENT_PROVIDER. The
+// configuration check has already refused the fake gateway outside
+// development.
+func newPaymentProvider(cfg config.PaymentConfig) (payment.PaymentProvider, error) {
+	switch cfg.Provider {
+	case "fake":
+		return payment.NewFakeProvider(cfg.WebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", cfg.Provider)
 	}
 }
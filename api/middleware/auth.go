@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/services"
+	"strings"
+)
+
+const userContextKey = "user"
+
+// Authenticate validates the bearer access token on the request and loads the
+// matching user into the gin context. Requests without a valid token, or for
+// users that are no longer active, are aborted with 401.
+func Authenticate(auth *services.AuthService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		scheme, tokenStr, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || tokenStr == "" {
+			fail(ctx, models.ErrUnauthenticated)
+			return
+		}
+
+		user, err := auth.Authenticate(ctx.Request.Context(), tokenStr)
+		if errors.Is(err, models.ErrAccountSuspended) || errors.Is(err, models.ErrPasswordReset) {
+			// The token is fine, so say why the account is refused.
+			fail(ctx, err)
+			return
+		}
+		if err != nil {
+			fail(ctx, models.ErrInvalidToken)
+			return
+		}
+
+		ctx.Set(userContextKey, user)
+		ctx.Next()
+	}
+}
+
+// Authorize only lets the request through when the authenticated user has one
+// of the given user types. It must run after Authenticate.
+func Authorize(userTypes ...models.UserType) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := CurrentUser(ctx)
+		if user == nil {
+			fail(ctx, models.ErrUnauthenticated)
+			return
+		}
+
+		for _, userType := range userTypes {
+			if user.UserType == userType {
+				ctx.Next()
+				return
+			}
+		}
+
+		fail(ctx, models.ErrForbidden)
+	}
+}
+
+// CurrentUser returns the user loaded by Authenticate, or nil when the route
+// is not authenticated.
+func CurrentUser(ctx *gin.Context) *models.User {
+	value, exists := ctx.Get(userContextKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
+
+// fail stops the request with err, which the Errors middleware turns into
+// the response.
+func fail(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/utility"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/memory"
+	"github.com/horlathunbhosun/reducing-food-waste/services"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+// createUser stores an active user of the type and returns an access token
+// for them.
+func createUser(t *testing.T, repos repository.Repositories, email, phone string, userType models.UserType) (*models.User, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	user := &models.User{FullName: "Jane Doe", Email: email, PasswordHash: "hash", PhoneNumber: phone, UserType: userType}
+	err := repos.Users.Create(ctx, user)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	err = repos.Users.UpdateStatus(ctx, user.Id, models.UserActive)
+	if err != nil {
+		t.Fatalf("activate user: %v", err)
+	}
+
+	token, err := utility.GenerateAccessToken([]byte(testSecret), time.Minute, user.Id, user.Email, string(userType))
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	return user, token
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := memory.New()
+	auth := services.NewAuthService(repos, mailer.NewCaptureSender(), config.AuthConfig{JWTSecret: testSecret, AccessTokenTTL: time.Minute})
+
+	_, adminToken := createUser(t, repos, "admin@example.com", "+2348000000001", models.ADMIN)
+	_, warriorToken := createUser(t, repos, "jane@example.com", "+2348000000002", models.WASTEWARRIOR)
+	suspended, suspendedToken := createUser(t, repos, "john@example.com", "+2348000000003", models.ADMIN)
+	now := time.Now()
+	suspended.SuspendedAt = &now
+	err := repos.Users.UpdateAccount(context.Background(), suspended, &models.AuditEntry{ActorID: suspended.Id, TargetUserID: suspended.Id, Action: models.AuditSuspendUser})
+	if err != nil {
+		t.Fatalf("suspend user: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(RequestID(slog.New(slog.NewTextHandler(io.Discard, nil))), Errors("json"))
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.GET("/admin", Authenticate(auth), Authorize(models.ADMIN), ok)
+	// Authorize on a route that skipped Authenticate has no user to check.
+	router.GET("/unauthenticated", Authorize(models.ADMIN), ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+		code   models.ErrorCode
+	}{
+		{"no token", "/admin", "", http.StatusUnauthorized, models.CodeUnauthenticated},
+		{"not a bearer token", "/admin", "Basic " + adminToken, http.StatusUnauthorized, models.CodeUnauthenticated},
+		{"invalid token", "/admin", "Bearer not-a-token", http.StatusUnauthorized, models.CodeInvalidToken},
+		{"wrong user type", "/admin", "Bearer " + warriorToken, http.StatusForbidden, models.CodeForbidden},
+		{"suspended", "/admin", "Bearer " + suspendedToken, http.StatusForbidden, models.CodeAccountSuspended},
+		{"without Authenticate", "/unauthenticated", "Bearer " + adminToken, http.StatusUnauthorized, models.CodeUnauthenticated},
+		{"allowed", "/admin", "Bearer " + adminToken, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			request.Header.Set("Authorization", tt.header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		var body response.JsonResponse
+		if recorder.Code != http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("%s: decode %s: %v", tt.name, recorder.Body, err)
+			}
+		}
+		if recorder.Code != tt.status || body.Code != string(tt.code) {
+			t.Errorf("%s: got %d %s, want %d %s", tt.name, recorder.Code, body.Code, tt.status, tt.code)
+		}
+	}
+}
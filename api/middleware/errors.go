@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"net/http"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// statusByCode maps every error code to its HTTP status. Codes missing here
+// are answered with 500.
+var statusByCode = map[models.ErrorCode]int{
+	models.CodeInvalidRequest:      http.StatusBadRequest,
+	models.CodeValidationFailed:    http.StatusBadRequest,
+	models.CodeRouteNotFound:       http.StatusNotFound,
+	models.CodeMethodNotAllowed:    http.StatusMethodNotAllowed,
+	models.CodeInternal:            http.StatusInternalServerError,
+	models.CodeUnauthenticated:     http.StatusUnauthorized,
+	models.CodeInvalidToken:        http.StatusUnauthorized,
+	models.CodeForbidden:           http.StatusForbidden,
+	models.CodeInvalidSignature:    http.StatusUnauthorized,
+	models.CodeEmailTaken:          http.StatusConflict,
+	models.CodePhoneTaken:          http.StatusConflict,
+	models.CodeInvalidLogin:        http.StatusUnauthorized,
+	models.CodeAccountInactive:     http.StatusForbidden,
+	models.CodeAccountSuspended:    http.StatusForbidden,
+	models.CodePasswordReset:       http.StatusForbidden,
+	models.CodeUserNotFound:        http.StatusNotFound,
+	models.CodeUserDeleted:         http.StatusConflict,
+	models.CodeUserSuspended:       http.StatusConflict,
+	models.CodeUserNotSuspended:    http.StatusConflict,
+	models.CodeUserTypeUnchanged:   http.StatusConflict,
+	models.CodeOwnAccount:          http.StatusForbidden,
+	models.CodeTokenNotFound:       http.StatusBadRequest,
+	models.CodeTokenExpired:        http.StatusBadRequest,
+	models.CodeInvalidRefresh:      http.StatusUnauthorized,
+	models.CodeInvalidCode:         http.StatusBadRequest,
+	models.CodeVerificationLocked:  http.StatusTooManyRequests,
+	models.CodePartnerNotFound:     http.StatusNotFound,
+	models.CodePartnerExists:       http.StatusConflict,
+	models.CodePartnerReviewed:     http.StatusConflict,
+	models.CodePartnerMissing:      http.StatusForbidden,
+	models.CodePartnerUnapproved:   http.StatusForbidden,
+	models.CodeMagicBagNotFound:    http.StatusNotFound,
+	models.CodeMagicBagWithdrawn:   http.StatusConflict,
+	models.CodeMagicBagSoldOut:     http.StatusConflict,
+	models.CodePickupEnded:         http.StatusConflict,
+	models.CodeProductNotFound:     http.StatusNotFound,
+	models.CodeInvalidBagItems:     http.StatusUnprocessableEntity,
+	models.CodeProductInUse:        http.StatusConflict,
+	models.CodeTransactionNotFound: http.StatusNotFound,
+	models.CodeDailyLimit:          http.StatusConflict,
+	models.CodePaymentDeclined:     http.StatusPaymentRequired,
+	models.CodePaymentState:        http.StatusConflict,
+	models.CodeAlreadyPickedUp:     http.StatusConflict,
+	models.CodeNotPickedUp:         http.StatusConflict,
+	models.CodeFeedbackNotFound:    http.StatusNotFound,
+	models.CodeFeedbackExists:      http.StatusConflict,
+}
+
+// Errors writes the response for the last error a handler added with
+// ctx.Error. Errors that are not a *models.Error, and any that map to a 5xx
+// status, are logged and reported to the client as INTERNAL_ERROR without
+// their message. format is "json" for the response envelope or "problem"
+// for RFC 7807 problem details, which a client can also ask for with its
+// Accept header. It must run after RequestID.
+func Errors(format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 {
+			return
+		}
+		err := ctx.Errors.Last().Err
+
+		var appErr *models.Error
+		if !errors.As(err, &appErr) {
+			appErr = models.ErrInternal
+		}
+		status, ok := statusByCode[appErr.Code]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		if status >= http.StatusInternalServerError {
+			logging.FromContext(ctx.Request.Context()).Error("request failed", "error", err)
+			appErr = models.ErrInternal
+		}
+
+		if ctx.Writer.Written() {
+			return
+		}
+
+		if format == "problem" || strings.Contains(ctx.GetHeader("Accept"), problemContentType) {
+			writeProblem(ctx, status, appErr)
+			return
+		}
+
+		var responseBody response.JsonResponse
+		responseBody.Error = true
+		responseBody.Code = string(appErr.Code)
+		responseBody.Message = appErr.Message
+		responseBody.Detail = appErr.Detail
+		if len(appErr.Fields) > 0 {
+			responseBody.Errors = appErr.Fields
+		}
+		responseBody.Status = false
+		responseBody.RequestID = CurrentRequestID(ctx)
+		ctx.AbortWithStatusJSON(status, responseBody)
+	}
+}
+
+// writeProblem answers with problem details. The type is about:blank, so the
+// title is the status text and the message goes in detail.
+func writeProblem(ctx *gin.Context, status int, appErr *models.Error) {
+	problem := response.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Code:      string(appErr.Code),
+		RequestID: CurrentRequestID(ctx),
+	}
+	if appErr.Detail != "" {
+		problem.Detail += ": " + appErr.Detail
+	}
+	if len(appErr.Fields) > 0 {
+		problem.Errors = appErr.Fields
+	}
+
+	// gin keeps a Content-Type that is already set when rendering JSON.
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(status, problem)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve answers a single request with a handler that fails with err.
+func serve(t *testing.T, err error) (int, response.JsonResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID(slog.New(slog.NewTextHandler(io.Discard, nil))), Errors("json"))
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.Error(err)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body response.JsonResponse
+	decodeErr := json.Unmarshal(recorder.Body.Bytes(), &body)
+	if decodeErr != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, decodeErr)
+	}
+	return recorder.Code, body
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{models.ErrProductNotFound, http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{models.ErrInvalidBagItems, http.StatusUnprocessableEntity, "INVALID_BAG_ITEMS"},
+		{fmt.Errorf("load bag: %w", models.ErrMagicBagWithdrawn), http.StatusConflict, "MAGIC_BAG_WITHDRAWN"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		status, body := serve(t, tt.err)
+		if status != tt.status || body.Code != tt.code {
+			t.Errorf("%v: got %d %s, want %d %s", tt.err, status, body.Code, tt.status, tt.code)
+		}
+		if tt.status == http.StatusInternalServerError && body.Message != models.ErrInternal.Message {
+			t.Errorf("%v: the message %q of an internal error was sent", tt.err, body.Message)
+		}
+	}
+}
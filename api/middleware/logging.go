@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// AccessLog writes one line per request. It logs the route pattern rather
+// than the path, so that whatever a client puts in a path stays out of the
+// logs, and never logs the query string. Successful requests to quietRoutes,
+// such as probes polled every few seconds, are logged at debug level. It
+// must run after RequestID.
+func AccessLog(quietRoutes ...string) gin.HandlerFunc {
+	quiet := make(map[string]bool, len(quietRoutes))
+	for _, route := range quietRoutes {
+		quiet[route] = true
+	}
+
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case quiet[route] && status < http.StatusBadRequest:
+			level = slog.LevelDebug
+		}
+
+		logging.FromContext(ctx.Request.Context()).LogAttrs(ctx.Request.Context(), level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		)
+	}
+}
+
+// Recover turns a panic in a handler into an INTERNAL_ERROR and logs it with
+// the stack. It replaces gin's recovery, which dumps the request headers. It
+// must run after Errors.
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered any) {
+		logging.FromContext(ctx.Request.Context()).Error("panic while serving request",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		fail(ctx, models.ErrInternal)
+	})
+}
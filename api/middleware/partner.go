@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/services"
+)
+
+const partnerContextKey = "partner"
+
+// RequireApprovedPartner loads the partner profile of the authenticated user
+// and refuses the request unless an admin has approved it. It must run after
+// Authenticate.
+func RequireApprovedPartner(partners *services.PartnerService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := CurrentUser(ctx)
+		if user == nil {
+			fail(ctx, models.ErrUnauthenticated)
+			return
+		}
+
+		partner, err := partners.GetByUserID(ctx.Request.Context(), user.Id)
+		if err != nil {
+			if errors.Is(err, models.ErrPartnerNotFound) {
+				err = models.ErrPartnerMissing
+			}
+			fail(ctx, err)
+			return
+		}
+
+		if partner.Status != models.PartnerApproved {
+			fail(ctx, models.ErrPartnerUnapproved)
+			return
+		}
+
+		ctx.Set(partnerContextKey, partner)
+		ctx.Next()
+	}
+}
+
+// CurrentPartner returns the partner loaded by RequireApprovedPartner.
+func CurrentPartner(ctx *gin.Context) *models.Partner {
+	value, exists := ctx.Get(partnerContextKey)
+	if !exists {
+		return nil
+	}
+	partner, _ := value.(*models.Partner)
+	return partner
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"log/slog"
+)
+
+const (
+	RequestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "request_id"
+)
+
+// RequestID gives every request an id, taken from the X-Request-ID header
+// when the caller sent a usable one, and echoes it in the response. The
+// request context carries a logger tagged with the id, so every line logged
+// while serving the request can be found by it.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx.Set(requestIDContextKey, id)
+		ctx.Header(RequestIDHeader, id)
+
+		requestLogger := logger.With("request_id", id)
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), requestLogger))
+
+		ctx.Next()
+	}
+}
+
+// CurrentRequestID returns the id set by RequestID.
+func CurrentRequestID(ctx *gin.Context) string {
+	return ctx.GetString(requestIDContextKey)
+}
+
+// validRequestID accepts ids of up to 64 letters, digits, dots, dashes and
+// underscores, so a caller cannot inject anything into the logs through it.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
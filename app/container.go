@@ -0,0 +1,64 @@
+// Package app wires the repositories, external services and business services
+// together into the single container the HTTP layer depends on.
+package app
+
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/metrics"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"github.com/horlathunbhosun/reducing-food-waste/services"
+	"log/slog"
+)
+
+// Container holds every dependency of the handlers and middleware. It is
+// built once at startup and shared by all requests.
+type Container struct {
+	Config   *config.Config
+	Logger   *slog.Logger
+	Repos    repository.Repositories
+	Mailer   mailer.Sender
+	Payments payment.PaymentProvider
+
+	// Lifecycle holds the readiness flag and the shutdown order of the
+	// background components.
+	Lifecycle *Lifecycle
+	// Metrics is served on /metrics and records the business events of the
+	// services.
+	Metrics *metrics.Metrics
+
+	Auth      *services.AuthService
+	Partners  *services.PartnerService
+	MagicBags *services.MagicBagService
+	Products  *services.ProductService
+	Purchases *services.PurchaseService
+	Feedback  *services.FeedbackService
+	Admin     *services.AdminService
+}
+
+// New builds the services on top of the given repositories, sending email
+// through mail and taking card payments through payments.
+func New(cfg *config.Config, logger *slog.Logger, repos repository.Repositories, mail mailer.Sender, payments payment.PaymentProvider) *Container {
+	m := metrics.New()
+	auth := services.NewAuthService(repos, mail, cfg.Auth)
+
+	return &Container{
+		Config:   cfg,
+		Logger:   logger,
+		Repos:    repos,
+		Mailer:   mail,
+		Payments: payments,
+
+		Lifecycle: &Lifecycle{},
+		Metrics:   m,
+
+		Auth:      auth,
+		Partners:  services.NewPartnerService(repos, mail),
+		MagicBags: services.NewMagicBagService(repos, m),
+		Products:  services.NewProductService(repos),
+		Purchases: services.NewPurchaseService(repos, payments, m),
+		Feedback:  services.NewFeedbackService(repos),
+		Admin:     services.NewAdminService(repos, auth),
+	}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each readiness check so that one hung dependency
+// cannot hold up the probe.
+const checkTimeout = 2 * time.Second
+
+// Lifecycle tracks whether the API should receive traffic and stops its
+// components in order when the process shuts down.
+type Lifecycle struct {
+	ready atomic.Bool
+
+	mu     sync.Mutex
+	hooks  []shutdownHook
+	checks []readinessCheck
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+type readinessCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one readiness check. Only the name and
+// status are served, since the error and latency can describe the
+// infrastructure; they are for the logs.
+type CheckResult struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Latency time.Duration `json:"-"`
+	Err     error         `json:"-"`
+}
+
+// Ready reports whether the API accepts traffic. It is false until startup
+// completes and again as soon as shutdown begins.
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
+
+func (l *Lifecycle) SetReady(ready bool) {
+	l.ready.Store(ready)
+}
+
+// AddCheck registers a dependency that has to work for the API to be ready,
+// such as the database.
+func (l *Lifecycle) AddCheck(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checks = append(l.checks, readinessCheck{name: name, fn: fn})
+}
+
+// Check runs every readiness check at once and reports each result. The API
+// is ready when it is neither starting nor draining and every check passes.
+func (l *Lifecycle) Check(ctx context.Context) ([]CheckResult, bool) {
+	l.mu.Lock()
+	checks := l.checks
+	l.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check readinessCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.fn(checkCtx)
+			results[i] = CheckResult{
+				Name:    check.name,
+				Status:  "ok",
+				Latency: time.Since(start),
+				Err:     err,
+			}
+			if err != nil {
+				results[i].Status = "failed"
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := l.Ready()
+	for _, result := range results {
+		if result.Err != nil {
+			ready = false
+		}
+	}
+	return results, ready
+}
+
+// OnShutdown registers a component to stop on shutdown. Components are
+// stopped in the reverse order they were registered, so anything registered
+// after the database is stopped while the database is still open.
+func (l *Lifecycle) OnShutdown(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Shutdown marks the API as not ready and stops every registered component.
+// A component that fails to stop does not keep the others running; all
+// errors are returned together.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.SetReady(false)
+
+	l.mu.Lock()
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
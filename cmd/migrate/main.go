@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/logging"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    roll back the last n migrations (default 1)
+  status      list migrations and whether they are applied`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	// Errors can quote the connection string, so they go through the
+	// redacting logger too.
+	logger, _ := logging.New(os.Stderr, "info", "text")
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal(logger, "could not load configuration", err)
+	}
+	err = cfg.Database.Validate()
+	if err != nil {
+		fatal(logger, "could not load configuration", err)
+	}
+	if cfg.Database.Driver == "memory" {
+		fatal(logger, "could not migrate", errors.New("the memory driver has no schema to migrate"))
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		fatal(logger, "could not connect to the database", err)
+	}
+	defer db.Close()
+
+	switch os.Args[1] {
+	case "up":
+		err = database.Migrate(db)
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				fatal(logger, "could not roll back", fmt.Errorf("invalid number of steps %q", os.Args[2]))
+			}
+		}
+		err = database.Rollback(db, steps)
+	case "status":
+		var states []database.MigrationState
+		states, err = database.Status(db)
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.Applied {
+				appliedAt = state.AppliedAt.Time.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s  %s\n", state.Version, state.Name, appliedAt)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		db.Close()
+		fatal(logger, "migrate "+os.Args[1]+" failed", err)
+	}
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
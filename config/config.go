@@ -1,9 +1,364 @@
+// Package config loads the settings of the API and the migrate command into a
+// single typed Config. Values come from, in increasing priority: the
+// defaults below, the YAML file named by CONFIG_FILE, a .env file in the
+// working directory and the process environment.
 package config
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
 
-func ConnectionStringAndDriver() (string, string) {
-	connStr := os.Getenv("DB_CONNECTION_STRING")
-	DBDriver := os.Getenv("DB_DRIVER")
-	return connStr, DBDriver
+type Config struct {
+	// Env is "development" or "production". Settings that are only safe on
+	// a developer's machine, such as the fake payment provider, are refused
+	// in production, so production needs a real payment provider first.
+	Env      string         `yaml:"env" env:"APP_ENV"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Mail     MailConfig     `yaml:"mail"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Payment  PaymentConfig  `yaml:"payment"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"SERVER_ADDR"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	// DrainDelay is how long the server keeps serving after it reports not
+	// ready, giving load balancers time to stop routing to it.
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SERVER_DRAIN_DELAY"`
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// workers get to finish once draining is over.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// ErrorFormat is "json" for the usual response envelope or "problem" for
+	// RFC 7807 problem details. Clients can ask for problem details per
+	// request with Accept: application/problem+json.
+	ErrorFormat string `yaml:"error_format" env:"SERVER_ERROR_FORMAT"`
+}
+
+type DatabaseConfig struct {
+	// Driver is one of "mysql", "postgres", "sqlite" or "memory".
+	Driver           string        `yaml:"driver" env:"DB_DRIVER"`
+	ConnectionString string        `yaml:"connection_string" env:"DB_CONNECTION_STRING"`
+	MaxOpenConns     int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns     int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+}
+
+type MailConfig struct {
+	// Backend is one of "smtp", "file" or "capture".
+	Backend  string `yaml:"backend" env:"MAIL_BACKEND"`
+	Host     string `yaml:"host" env:"MAIL_HOST"`
+	Port     int    `yaml:"port" env:"MAIL_PORT"`
+	Username string `yaml:"username" env:"MAIL_USERNAME"`
+	Password string `yaml:"password" env:"MAIL_PASSWORD"`
+	Sender   string `yaml:"sender" env:"MAIL_SENDER"`
+	// Dir is where the file backend writes its maildir.
+	Dir string `yaml:"dir" env:"MAIL_DIR"`
+
+	OutboxWorkers     int `yaml:"outbox_workers" env:"MAIL_OUTBOX_WORKERS"`
+	OutboxMaxAttempts int `yaml:"outbox_max_attempts" env:"MAIL_OUTBOX_MAX_ATTEMPTS"`
+}
+
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret" env:"JWT_SECRET"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+	// The verification settings apply to every emailed code: email
+	// verification, password reset and email change.
+	VerificationCodeTTL time.Duration `yaml:"verification_code_ttl" env:"VERIFICATION_CODE_TTL"`
+	// VerificationMaxAttempts is how many codes a user may try before codes
+	// of that purpose are locked for VerificationLockout.
+	VerificationMaxAttempts int           `yaml:"verification_max_attempts" env:"VERIFICATION_MAX_ATTEMPTS"`
+	VerificationLockout     time.Duration `yaml:"verification_lockout" env:"VERIFICATION_LOCKOUT"`
+}
+
+type PaymentConfig struct {
+	// Provider is the payment gateway. Only "fake", the in-process gateway
+	// for development, exists so far.
+	Provider      string `yaml:"provider" env:"PAYMENT_PROVIDER"`
+	WebhookSecret string `yaml:"webhook_secret" env:"PAYMENT_WEBHOOK_SECRET"`
+}
+
+type LogConfig struct {
+	// Level is one of "debug", "info", "warn" or "error".
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Format is "json" or "text".
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// Default returns the configuration used for every setting that is not set
+// anywhere else.
+func Default() *Config {
+	return &Config{
+		Env: "development",
+		Server: ServerConfig{
+			Addr:              ":9090",
+			ReadHeaderTimeout: 10 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			ErrorFormat:       "json",
+		},
+		Database: DatabaseConfig{
+			Driver:       "mysql",
+			MaxOpenConns: 10,
+			MaxIdleConns: 5,
+		},
+		Mail: MailConfig{
+			Backend:           "smtp",
+			Dir:               "tmp/mail",
+			OutboxWorkers:     2,
+			OutboxMaxAttempts: 8,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:          15 * time.Minute,
+			RefreshTokenTTL:         30 * 24 * time.Hour,
+			VerificationCodeTTL:     30 * time.Minute,
+			VerificationMaxAttempts: 5,
+			VerificationLockout:     15 * time.Minute,
+		},
+		Payment: PaymentConfig{
+			Provider: "fake",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load reads the configuration without validating it, so that a command can
+// check only the sections it uses. Values that cannot be parsed are reported
+// together.
+func Load() (*Config, error) {
+	_ = godotenv.Load()
+
+	cfg := Default()
+
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		err = yaml.Unmarshal(content, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", file, err)
+		}
+	}
+
+	var errs []error
+	applyEnv(reflect.ValueOf(cfg).Elem(), &errs)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overwrites every field tagged with env whose variable is set.
+// Empty variables count as unset, so a blank line in .env keeps the default.
+func applyEnv(v reflect.Value, errs *[]error) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyEnv(field, errs)
+			continue
+		}
+
+		name := v.Type().Field(i).Tag.Get("env")
+		value := os.Getenv(name)
+		if name == "" || value == "" {
+			continue
+		}
+
+		switch {
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %q is not a duration such as 15m or 72h", name, value))
+				continue
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %q is not a number", name, value))
+				continue
+			}
+			field.SetInt(int64(n))
+		case field.Kind() == reflect.String:
+			field.SetString(value)
+		}
+	}
+}
+
+// Validate checks the whole configuration and reports every problem at once.
+func (c *Config) Validate() error {
+	errs := []error{
+		c.Server.Validate(),
+		c.Database.Validate(),
+		c.Mail.Validate(),
+		c.Auth.Validate(),
+		c.Payment.Validate(),
+		c.Log.Validate(),
+	}
+	switch c.Env {
+	case "development":
+	case "production":
+		if c.Payment.Provider == "fake" {
+			errs = append(errs, errors.New("PAYMENT_PROVIDER: the fake provider is only allowed with APP_ENV=development"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV: unknown environment %q", c.Env))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+func (c ServerConfig) Validate() error {
+	var errs []error
+
+	if c.Addr == "" {
+		errs = append(errs, errors.New("SERVER_ADDR is required"))
+	}
+	if c.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("SERVER_READ_HEADER_TIMEOUT must be positive"))
+	}
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("SERVER_DRAIN_DELAY must not be negative"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SERVER_SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.ErrorFormat != "json" && c.ErrorFormat != "problem" {
+		errs = append(errs, fmt.Errorf("SERVER_ERROR_FORMAT: unknown format %q", c.ErrorFormat))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c DatabaseConfig) Validate() error {
+	var errs []error
+
+	switch c.Driver {
+	case "memory":
+		return nil
+	case "mysql", "postgres", "sqlite":
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: unsupported driver %q", c.Driver))
+	}
+	if c.ConnectionString == "" {
+		errs = append(errs, errors.New("DB_CONNECTION_STRING is required"))
+	}
+	if c.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS must be at least 1"))
+	}
+	if c.MaxIdleConns < 0 || c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS"))
+	}
+	if c.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("DB_CONN_MAX_LIFETIME must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c MailConfig) Validate() error {
+	var errs []error
+
+	switch c.Backend {
+	case "smtp":
+		if c.Host == "" {
+			errs = append(errs, errors.New("MAIL_HOST is required for the smtp backend"))
+		}
+		if c.Port < 1 || c.Port > 65535 {
+			errs = append(errs, errors.New("MAIL_PORT must be a port number for the smtp backend"))
+		}
+		if c.Sender == "" {
+			errs = append(errs, errors.New("MAIL_SENDER is required for the smtp backend"))
+		}
+	case "file":
+		if c.Dir == "" {
+			errs = append(errs, errors.New("MAIL_DIR is required for the file backend"))
+		}
+	case "capture":
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_BACKEND: unknown backend %q", c.Backend))
+	}
+	if c.OutboxWorkers < 1 {
+		errs = append(errs, errors.New("MAIL_OUTBOX_WORKERS must be at least 1"))
+	}
+	if c.OutboxMaxAttempts < 1 {
+		errs = append(errs, errors.New("MAIL_OUTBOX_MAX_ATTEMPTS must be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c AuthConfig) Validate() error {
+	var errs []error
+
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	}
+	if c.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("ACCESS_TOKEN_TTL must be positive"))
+	}
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL"))
+	}
+	if c.VerificationCodeTTL <= 0 {
+		errs = append(errs, errors.New("VERIFICATION_CODE_TTL must be positive"))
+	}
+	if c.VerificationMaxAttempts < 1 {
+		errs = append(errs, errors.New("VERIFICATION_MAX_ATTEMPTS must be at least 1"))
+	}
+	if c.VerificationLockout <= 0 {
+		errs = append(errs, errors.New("VERIFICATION_LOCKOUT must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c PaymentConfig) Validate() error {
+	var errs []error
+
+	switch c.Provider {
+	case "fake":
+	default:
+		errs = append(errs, fmt.Errorf("PAYMENT_PROVIDER: unknown provider %q", c.Provider))
+	}
+	// Webhooks change payments, restock bags and lift purchase limits, so
+	// they must not be signed with a key anyone can guess.
+	if c.WebhookSecret == "" {
+		errs = append(errs, errors.New("PAYMENT_WEBHOOK_SECRET is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c LogConfig) Validate() error {
+	var errs []error
+
+	switch strings.ToLower(c.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: unknown level %q", c.Level))
+	}
+	switch strings.ToLower(c.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT: unknown format %q", c.Format))
+	}
+
+	return errors.Join(errs...)
 }
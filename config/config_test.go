@@ -0,0 +1,100 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// setRequired sets the settings that have no default.
+func setRequired(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_CONNECTION_STRING", "user:password@tcp(localhost:3306)/food_waste")
+	t.Setenv("MAIL_HOST", "localhost")
+	t.Setenv("MAIL_PORT", "1025")
+	t.Setenv("MAIL_SENDER", "no-reply@example.com")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "secret")
+}
+
+func TestDefaultConfig(t *testing.T) {
+	setRequired(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Env != "development" || cfg.Payment.Provider != "fake" {
+		t.Fatalf("got env %q with provider %q, want development with fake", cfg.Env, cfg.Payment.Provider)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		t.Fatalf("the default configuration is invalid: %v", err)
+	}
+}
+
+func TestProductionRefusesFakePayments(t *testing.T) {
+	setRequired(t)
+	t.Setenv("APP_ENV", "production")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "PAYMENT_PROVIDER") {
+		t.Fatalf("got %v, want the fake provider refused", err)
+	}
+}
+
+func TestLoadReportsEveryUnparsableValue(t *testing.T) {
+	setRequired(t)
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load accepted values it cannot parse")
+	}
+	for _, name := range []string{"ACCESS_TOKEN_TTL", "DB_MAX_OPEN_CONNS"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("got %v, want %s reported", err, name)
+		}
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	setRequired(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.Env = "staging"
+	cfg.Database.Driver = "oracle"
+	cfg.Auth.JWTSecret = ""
+	cfg.Auth.AccessTokenTTL = 0
+	cfg.Log.Level = "loud"
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted an invalid configuration")
+	}
+	want := []string{"APP_ENV", "DB_DRIVER", "JWT_SECRET", "ACCESS_TOKEN_TTL", "LOG_LEVEL"}
+	for _, name := range want {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("got %v, want %s reported", err, name)
+		}
+	}
+	// One line to introduce the problems, then one per problem.
+	if lines := strings.Count(err.Error(), "\n"); lines != len(want) {
+		t.Errorf("got %d problems reported, want %d:\n%v", lines, len(want), err)
+	}
+
+	// A command can check only the sections it uses.
+	err = cfg.Mail.Validate()
+	if err != nil {
+		t.Errorf("Mail.Validate: got %v, want the mail section valid", err)
+	}
+}
@@ -1,191 +1,125 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/horlathunbhosun/reducing-food-waste/config"
-	"github.com/joho/godotenv"
-
-	"log"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
-
-func InitDB() {
+// DB is a connection pool together with the dialect of the database behind
+// it. Queries are written with ? placeholders; the query methods rebind them
+// for the dialect before they reach the driver.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
 
-	err := godotenv.Load()
+// Open connects to the database without touching the schema. It is shared
+// by the API and the migrate command.
+func Open(cfg config.DatabaseConfig) (*DB, error) {
+	dialect, err := DialectFor(cfg.Driver)
 	if err != nil {
-		return
+		return nil, err
 	}
-	connStr, dbServer := config.ConnectionStringAndDriver()
 
-	println(connStr, dbServer)
-	DB, err = sql.Open(dbServer, connStr)
+	connStr := cfg.ConnectionString
+	if dialect.Name() == "sqlite" {
+		connStr = sqliteDSN(connStr)
+	}
 
+	db, err := sql.Open(dialect.driver(), connStr)
 	if err != nil {
-		panic("Could not connect to the database")
+		return nil, err
 	}
 
-	DB.SetMaxOpenConns(10)
-	DB.SetMaxIdleConns(5)
-
-	createTables()
-}
-
-func createTables() {
-	createUsersTable()
-	createUserTokensTable()
-	createPartnersTable()
-	createProductsTable()
-	createMagicBagsTable()
-	createTransactionsTable()
-	createMagicBagProductsTable()
-	createFeedbackTable()
-}
-
-func createUsersTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-      id INTEGER PRIMARY KEY AUTO_INCREMENT,
-    fullname VARCHAR(30) NOT NULL,
-    email VARCHAR(255) UNIQUE,
-    phone_number VARCHAR(40) UNIQUE,
-	status ENUM('active', 'inactive') DEFAULT 'inactive',
-    user_type ENUM('waste_warrior', 'partners', 'admin') NOT NULL,
-    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-	)`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not users table")
+	if dialect.Name() == "sqlite" {
+		// SQLite allows a single writer; one connection queues writes in the
+		// pool instead of failing them with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
 	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	return &DB{DB: db, Dialect: dialect}, nil
 }
 
-func createUserTokensTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS user_tokens (
-      id INTEGER PRIMARY KEY AUTO_INCREMENT,
-	user_id INTEGER NOT NULL,
-    email VARCHAR(30) NOT NULL,
-    token VARCHAR(50) UNIQUE ,
-	expire_at DATETIME NOT NULL,
-    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-
-	)`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not users table")
-	}
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.Dialect.Rebind(query), args...)
 }
 
-func createPartnersTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS partners (
-	  id INTEGER PRIMARY KEY AUTO_INCREMENT,
-	business_number VARCHAR(30) NOT NULL,
-	user_id INTEGER NOT NULL,   
-	logo VARCHAR(50) NULL,
-	address VARCHAR(40) NULL,
-	date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	)`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not partners table")
-	}
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.Dialect.Rebind(query), args...)
 }
 
-func createProductsTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS products (
-	  id INTEGER PRIMARY KEY AUTO_INCREMENT,
-		name VARCHAR(30) NOT NULL,
-	date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-	)`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not products table")
-	}
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.Dialect.Rebind(query), args...)
 }
 
-func createMagicBagsTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS magic_bags (
-	  id INTEGER PRIMARY KEY AUTO_INCREMENT,
-	  bag_price FLOAT,
-	  partner_id INTEGER NOT NULL,
-	date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
-	    )`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not magic_bags table")
-	}
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
 }
 
-func createTransactionsTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS transactions (
-	  id INTEGER PRIMARY KEY AUTO_INCREMENT,
-	  amount FLOAT,
-	magic_bag_id INTEGER NOT NULL,
-	user_id INTEGER NOT NULL,
-	date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-	UNIQUE KEY waste_warrior_purchase_unique (user_id, magic_bag_id, date_created)
-	)`
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not transactions table")
-	}
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
 }
 
-func createMagicBagProductsTable() {
-	query := `CREATE TABLE IF NOT EXISTS magic_bag_products (
-    	id INTEGER PRIMARY KEY AUTO_INCREMENT,
-    	quantity INTEGER,
-    	magic_bag_id INTEGER NOT NULL,
-    	product_id INTEGER NOT NULL,
-    	date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-    	date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    	FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
-    	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE   )`
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
 
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalln(err)
-		panic("Can not transactions table")
-	}
+// Insert runs an INSERT statement and returns the id of the new row.
+func (db *DB) Insert(ctx context.Context, query string, args ...any) (int64, error) {
+	return db.Dialect.insertID(ctx, db.DB, db.Dialect.Rebind(query), args)
 }
 
-func createFeedbackTable() {
-	query := `CREATE TABLE IF NOT EXISTS feedback (
-		id INTEGER PRIMARY KEY AUTO_INCREMENT,
-		rating  INTEGER DEFAULT 0,
-    	comment LONGTEXT  NULL,
-		transaction_id INTEGER NOT NULL,
-		date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
-		date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
-    )`
-	_, err := DB.Exec(query)
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
 	if err != nil {
-		log.Fatalln(err)
-		panic("Can not feedback table")
+		return nil, err
 	}
+	return &Tx{Tx: tx, Dialect: db.Dialect}, nil
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// Tx is a transaction that rebinds its queries like DB does.
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Dialect.Rebind(query), args...)
 }
 
-//
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+// Insert runs an INSERT statement and returns the id of the new row.
+func (tx *Tx) Insert(ctx context.Context, query string, args ...any) (int64, error) {
+	return tx.Dialect.insertID(ctx, tx.Tx, tx.Dialect.Rebind(query), args)
+}
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect covers the SQL that differs between the supported databases. The
+// repositories write portable SQL and ask the dialect for the rest.
+type Dialect interface {
+	// Name is the DB_DRIVER value and the migrations directory.
+	Name() string
+
+	// Rebind rewrites a query written with ? placeholders into the
+	// placeholder style of the database.
+	Rebind(query string) string
+
+	// ForUpdate is appended to a SELECT to lock the rows it reads until the
+	// transaction ends. skipLocked skips rows another transaction holds.
+	ForUpdate(skipLocked bool) string
+
+	// IsUniqueViolation reports whether err was caused by a unique index.
+	IsUniqueViolation(err error) bool
+
+	// IgnoreConflict is appended to an INSERT so that it does nothing when a
+	// row with the same value in column already exists.
+	IgnoreConflict(column string) string
+
+	// CompareTime wraps a time column or placeholder so that comparing and
+	// sorting by it goes by the time rather than how it is stored.
+	CompareTime(expr string) string
+
+	// EqualFold compares column with a placeholder regardless of case, the
+	// way the unique index on users.email does, so that it can use it.
+	EqualFold(column string) string
+
+	// driver is the database/sql driver name.
+	driver() string
+
+	// insertID runs an INSERT that has already been rebound and returns the
+	// id of the new row.
+	insertID(ctx context.Context, q execQuerier, query string, args []any) (int64, error)
+
+	// withLock runs fn while holding a lock that keeps other processes from
+	// migrating the same database.
+	withLock(ctx context.Context, conn *sql.Conn, fn func() error) error
+
+	// exec runs a migration script.
+	exec(ctx context.Context, conn *sql.Conn, script string) error
+}
+
+// DialectFor returns the dialect for a DB_DRIVER value.
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	case "postgres":
+		return postgresDialect{}, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// lastInsertID reads the new id from the result, for drivers that report it.
+func lastInsertID(ctx context.Context, q execQuerier, query string, args []any) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+func (mysqlDialect) driver() string { return "mysql" }
+
+func (mysqlDialect) insertID(ctx context.Context, q execQuerier, query string, args []any) (int64, error) {
+	return lastInsertID(ctx, q, query, args)
+}
+
+func (mysqlDialect) ForUpdate(skipLocked bool) string {
+	if skipLocked {
+		return "FOR UPDATE SKIP LOCKED"
+	}
+	return "FOR UPDATE"
+}
+
+func (mysqlDialect) IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+func (mysqlDialect) IgnoreConflict(column string) string {
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", column, column)
+}
+
+func (mysqlDialect) CompareTime(expr string) string { return expr }
+
+// EqualFold relies on the case-insensitive collation the tables default to.
+func (mysqlDialect) EqualFold(column string) string { return column + " = ?" }
+
+func (mysqlDialect) withLock(ctx context.Context, conn *sql.Conn, fn func() error) error {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", migrationLockName).Scan(&locked)
+	if err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return errors.New("timed out waiting for the migration lock")
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName)
+
+	return fn()
+}
+
+// exec runs the script one statement at a time, since the driver does not
+// accept several statements in a single Exec.
+func (mysqlDialect) exec(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range strings.Split(script, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteDialect targets a single SQLite file. Write transactions take the
+// database lock when they begin (see sqliteDSN), so rows never need locking
+// one by one.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) driver() string { return "sqlite" }
+
+func (sqliteDialect) insertID(ctx context.Context, q execQuerier, query string, args []any) (int64, error) {
+	return lastInsertID(ctx, q, query, args)
+}
+
+func (sqliteDialect) ForUpdate(skipLocked bool) string { return "" }
+
+func (sqliteDialect) IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+func (sqliteDialect) IgnoreConflict(column string) string {
+	return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", column)
+}
+
+// CompareTime converts to a Julian day number. Times are stored as text, and
+// the layout of CURRENT_TIMESTAMP defaults has no zone or fraction while
+// bound times have both, so the texts do not compare the same way the times
+// do.
+func (sqliteDialect) CompareTime(expr string) string {
+	return fmt.Sprintf("julianday(%s)", expr)
+}
+
+func (sqliteDialect) EqualFold(column string) string { return column + " = ? COLLATE NOCASE" }
+
+// withLock runs fn inside an immediate transaction, which holds the write
+// lock on the file and also makes each migration run all or nothing.
+func (sqliteDialect) withLock(ctx context.Context, conn *sql.Conn, fn func() error) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	if err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+// exec runs the whole script at once; the driver accepts several statements
+// and splitting on ";" would break trigger bodies.
+func (sqliteDialect) exec(ctx context.Context, conn *sql.Conn, script string) error {
+	_, err := conn.ExecContext(ctx, script)
+	return err
+}
+
+// sqliteDSN turns on foreign keys, waits on a busy database instead of
+// failing, takes the write lock when a transaction begins and stores times in
+// the same layout as CURRENT_TIMESTAMP so they compare correctly.
+func sqliteDSN(connStr string) string {
+	separator := "?"
+	if strings.Contains(connStr, "?") {
+		separator = "&"
+	}
+	return connStr + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+}
+
+// postgresDialect targets PostgreSQL through the pgx driver.
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+// Rebind numbers the placeholders $1, $2, ... in order. Question marks inside
+// quoted strings and identifiers are left alone.
+func (postgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (postgresDialect) driver() string { return "pgx" }
+
+func (postgresDialect) ForUpdate(skipLocked bool) string {
+	if skipLocked {
+		return "FOR UPDATE SKIP LOCKED"
+	}
+	return "FOR UPDATE"
+}
+
+// IsUniqueViolation checks for SQLSTATE 23505 (unique_violation).
+func (postgresDialect) IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (postgresDialect) IgnoreConflict(column string) string {
+	return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", column)
+}
+
+func (postgresDialect) CompareTime(expr string) string { return expr }
+
+func (postgresDialect) EqualFold(column string) string {
+	return fmt.Sprintf("LOWER(%s) = LOWER(?)", column)
+}
+
+// insertID asks for the id with RETURNING, since the driver does not
+// support LastInsertId.
+func (postgresDialect) insertID(ctx context.Context, q execQuerier, query string, args []any) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// withLock holds a session advisory lock keyed on the lock name.
+func (postgresDialect) withLock(ctx context.Context, conn *sql.Conn, fn func() error) error {
+	lockCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	_, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock(hashtext($1))", migrationLockName)
+	if err != nil {
+		if errors.Is(lockCtx.Err(), context.DeadlineExceeded) {
+			return errors.New("timed out waiting for the migration lock")
+		}
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", migrationLockName)
+
+	return fn()
+}
+
+// exec runs the whole script at once; without arguments the driver uses the
+// simple query protocol, which accepts several statements.
+func (postgresDialect) exec(ctx context.Context, conn *sql.Conn, script string) error {
+	_, err := conn.ExecContext(ctx, script)
+	return err
+}
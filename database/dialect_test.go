@@ -0,0 +1,39 @@
+package database
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM users WHERE id = ?", "SELECT * FROM users WHERE id = $1"},
+		{"UPDATE users SET email = ?, status = ? WHERE id = ?", "UPDATE users SET email = $1, status = $2 WHERE id = $3"},
+		{"SELECT * FROM bags WHERE title = 'why?' AND id = ?", "SELECT * FROM bags WHERE title = 'why?' AND id = $1"},
+		{`SELECT "odd?column" FROM bags WHERE id IN (?, ?)`, `SELECT "odd?column" FROM bags WHERE id IN ($1, $2)`},
+		{"SELECT ? || 'it''s?' || ?", "SELECT $1 || 'it''s?' || $2"},
+	}
+
+	postgres, err := DialectFor("postgres")
+	if err != nil {
+		t.Fatalf("DialectFor: %v", err)
+	}
+	for _, tt := range tests {
+		if got := postgres.Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, driver := range []string{"mysql", "sqlite"} {
+		dialect, err := DialectFor(driver)
+		if err != nil {
+			t.Fatalf("DialectFor(%s): %v", driver, err)
+		}
+		for _, tt := range tests {
+			if got := dialect.Rebind(tt.query); got != tt.query {
+				t.Errorf("%s Rebind(%q) = %q, want it unchanged", driver, tt.query, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFS embed.FS
+
+const migrationLockName = "schema_migrations"
+
+var ErrChecksumMismatch = errors.New("applied migration has been modified")
+
+// Migration is a single versioned schema change loaded from the embedded
+// migrations directory of a dialect. Files are named <version>_<name>.up.sql
+// and <version>_<name>.down.sql. A version means the same schema in every
+// dialect, so a dialect added later may start from a single migration that
+// creates the schema as of that version.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationState reports whether a known migration has been applied.
+type MigrationState struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt sql.NullTime
+}
+
+// LoadMigrations reads the embedded migration files of a dialect ordered by
+// version.
+func LoadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", dialect.Name())
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", fileName)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version", fileName)
+		}
+
+		content, err := fs.ReadFile(migrationFS, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrate applies every pending migration in version order. Migrations that
+// were already applied are checked against their recorded checksum first so
+// an edited migration is reported instead of silently skipped.
+func Migrate(db *DB) error {
+	return withMigrationLock(db, func(conn *sql.Conn) error {
+		migrations, applied, err := loadAndVerify(conn, db.Dialect)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, done := applied[m.Version]; done {
+				continue
+			}
+			if err := db.Dialect.exec(context.Background(), conn, m.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			_, err := conn.ExecContext(context.Background(),
+				db.Dialect.Rebind("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)"),
+				m.Version, m.Name, m.Checksum)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Rollback reverts the given number of most recently applied migrations.
+func Rollback(db *DB, steps int) error {
+	return withMigrationLock(db, func(conn *sql.Conn) error {
+		migrations, applied, err := loadAndVerify(conn, db.Dialect)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, done := applied[m.Version]; !done {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s: missing down file", m.Version, m.Name)
+			}
+			if err := db.Dialect.exec(context.Background(), conn, m.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			_, err := conn.ExecContext(context.Background(), db.Dialect.Rebind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+			if err != nil {
+				return err
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Status lists every known migration and whether it has been applied.
+func Status(db *DB) ([]MigrationState, error) {
+	var states []MigrationState
+
+	err := withMigrationLock(db, func(conn *sql.Conn) error {
+		migrations, applied, err := loadAndVerify(conn, db.Dialect)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			appliedAt, done := applied[m.Version]
+			states = append(states, MigrationState{
+				Version:   m.Version,
+				Name:      m.Name,
+				Applied:   done,
+				AppliedAt: sql.NullTime{Time: appliedAt, Valid: done},
+			})
+		}
+		return nil
+	})
+
+	return states, err
+}
+
+// PendingMigrations counts the known migrations that have not been applied.
+// Unlike Status it does not take the migration lock, so it is cheap enough
+// for a readiness probe.
+func PendingMigrations(ctx context.Context, db *DB) (int, error) {
+	migrations, err := LoadMigrations(db.Dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return 0, err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the dialect's
+// migration lock so that several instances booting at once do not apply the
+// same migration.
+func withMigrationLock(db *DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return db.Dialect.withLock(ctx, conn, func() error {
+		_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    version BIGINT PRIMARY KEY,
+		    name VARCHAR(255) NOT NULL,
+		    checksum CHAR(64) NOT NULL,
+		    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+		if err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+func loadAndVerify(conn *sql.Conn, dialect Dialect) ([]Migration, map[int64]time.Time, error) {
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	checksums := make(map[int64]string)
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var checksum string
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &checksum, &appliedAt); err != nil {
+			return nil, nil, err
+		}
+		checksums[version] = checksum
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range migrations {
+		checksum, done := checksums[m.Version]
+		if done && checksum != m.Checksum {
+			return nil, nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, m.Version, m.Name)
+		}
+	}
+
+	return migrations, applied, nil
+}
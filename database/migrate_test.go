@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := Open(config.DatabaseConfig{Driver: "sqlite", ConnectionString: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func countApplied(t *testing.T, db *DB) int {
+	t.Helper()
+
+	states, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	applied := 0
+	for _, state := range states {
+		if state.Applied {
+			applied++
+		}
+	}
+	return applied
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrations, err := LoadMigrations(db.Dialect)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+
+	err = Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if applied := countApplied(t, db); applied != len(migrations) {
+		t.Fatalf("got %d migrations applied, want %d", applied, len(migrations))
+	}
+	pending, err := PendingMigrations(ctx, db)
+	if err != nil || pending != 0 {
+		t.Fatalf("PendingMigrations: got %d, %v, want none", pending, err)
+	}
+
+	// Nothing is left to apply the second time.
+	err = Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+
+	err = Rollback(db, 1)
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	pending, err = PendingMigrations(ctx, db)
+	if err != nil || pending != 1 {
+		t.Fatalf("PendingMigrations after one rollback: got %d, %v, want 1", pending, err)
+	}
+
+	// Every down file must undo its up file for the schema to come back.
+	err = Rollback(db, len(migrations))
+	if err != nil {
+		t.Fatalf("Rollback all: %v", err)
+	}
+	if applied := countApplied(t, db); applied != 0 {
+		t.Fatalf("got %d migrations applied after rolling back all, want none", applied)
+	}
+	_, err = db.ExecContext(ctx, "SELECT 1 FROM users")
+	if err == nil {
+		t.Fatal("the users table is still there after rolling back all")
+	}
+
+	err = Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate after rolling back: %v", err)
+	}
+}
+
+func TestMigrateChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	err := Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE schema_migrations SET checksum = ? WHERE version = (SELECT MIN(version) FROM schema_migrations)", "edited")
+	if err != nil {
+		t.Fatalf("edit checksum: %v", err)
+	}
+
+	err = Migrate(db)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Migrate: got %v, want ErrChecksumMismatch", err)
+	}
+	err = Rollback(db, 1)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Rollback: got %v, want ErrChecksumMismatch", err)
+	}
+}
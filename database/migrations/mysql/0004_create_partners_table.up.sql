@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS partners (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    business_number VARCHAR(30) NOT NULL,
+    user_id INTEGER NOT NULL,
+    logo VARCHAR(50) NULL,
+    address VARCHAR(40) NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
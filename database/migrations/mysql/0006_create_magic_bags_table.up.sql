@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS magic_bags (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    bag_price FLOAT,
+    partner_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
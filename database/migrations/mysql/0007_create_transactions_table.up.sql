@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    amount FLOAT,
+    magic_bag_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY waste_warrior_purchase_unique (user_id, magic_bag_id, date_created)
+);
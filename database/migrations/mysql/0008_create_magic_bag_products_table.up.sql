@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS magic_bag_products (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    quantity INTEGER,
+    magic_bag_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
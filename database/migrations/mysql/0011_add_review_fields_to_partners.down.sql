@@ -0,0 +1,7 @@
+ALTER TABLE partners
+    DROP INDEX partners_user_id_unique,
+    DROP COLUMN reviewed_at,
+    DROP COLUMN rejection_reason,
+    DROP COLUMN status,
+    MODIFY logo VARCHAR(50) NULL,
+    MODIFY address VARCHAR(40) NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE partners
+    MODIFY logo VARCHAR(255) NULL,
+    MODIFY address VARCHAR(255) NULL,
+    ADD COLUMN status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending' AFTER address,
+    ADD COLUMN rejection_reason VARCHAR(255) NULL AFTER status,
+    ADD COLUMN reviewed_at DATETIME NULL AFTER rejection_reason,
+    ADD UNIQUE KEY partners_user_id_unique (user_id);
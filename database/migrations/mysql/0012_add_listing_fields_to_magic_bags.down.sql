@@ -0,0 +1,12 @@
+ALTER TABLE magic_bag_products
+    DROP INDEX magic_bag_products_unique,
+    MODIFY quantity INTEGER;
+
+ALTER TABLE magic_bags
+    DROP COLUMN status,
+    DROP COLUMN pickup_end,
+    DROP COLUMN pickup_start,
+    DROP COLUMN quantity,
+    DROP COLUMN description,
+    DROP COLUMN title,
+    MODIFY bag_price FLOAT;
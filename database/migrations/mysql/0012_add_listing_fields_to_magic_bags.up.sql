@@ -0,0 +1,12 @@
+ALTER TABLE magic_bags
+    MODIFY bag_price DECIMAL(10, 2) NOT NULL,
+    ADD COLUMN title VARCHAR(100) NOT NULL DEFAULT '' AFTER id,
+    ADD COLUMN description TEXT NULL AFTER title,
+    ADD COLUMN quantity INTEGER NOT NULL DEFAULT 0 AFTER bag_price,
+    ADD COLUMN pickup_start DATETIME NULL AFTER quantity,
+    ADD COLUMN pickup_end DATETIME NULL AFTER pickup_start,
+    ADD COLUMN status ENUM('active', 'withdrawn') NOT NULL DEFAULT 'active' AFTER pickup_end;
+
+ALTER TABLE magic_bag_products
+    MODIFY quantity INTEGER NOT NULL DEFAULT 1,
+    ADD UNIQUE KEY magic_bag_products_unique (magic_bag_id, product_id);
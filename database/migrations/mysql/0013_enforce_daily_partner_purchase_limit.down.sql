@@ -0,0 +1,11 @@
+ALTER TABLE transactions
+    ADD UNIQUE KEY waste_warrior_purchase_unique (user_id, magic_bag_id, date_created);
+
+ALTER TABLE transactions
+    DROP INDEX transactions_daily_partner_idx,
+    DROP FOREIGN KEY transactions_partner_id_fk,
+    DROP COLUMN purchase_day,
+    DROP COLUMN partner_id;
+
+ALTER TABLE partners
+    DROP COLUMN timezone;
@@ -0,0 +1,22 @@
+-- The one bag per partner per day limit is checked when a bag is reserved,
+-- not by a unique key: failed and refunded purchases must not count, and
+-- existing rows may already hold two purchases on the same day.
+ALTER TABLE partners
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC' AFTER address;
+
+ALTER TABLE transactions
+    ADD COLUMN partner_id INTEGER NULL AFTER user_id,
+    ADD COLUMN purchase_day DATE NULL AFTER partner_id;
+
+UPDATE transactions t
+    JOIN magic_bags mb ON mb.id = t.magic_bag_id
+    SET t.partner_id = mb.partner_id, t.purchase_day = DATE(t.date_created);
+
+ALTER TABLE transactions
+    MODIFY partner_id INTEGER NOT NULL,
+    MODIFY purchase_day DATE NOT NULL,
+    ADD CONSTRAINT transactions_partner_id_fk FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE,
+    ADD INDEX transactions_daily_partner_idx (user_id, partner_id, purchase_day);
+
+ALTER TABLE transactions
+    DROP INDEX waste_warrior_purchase_unique;
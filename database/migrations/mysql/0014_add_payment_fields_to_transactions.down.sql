@@ -0,0 +1,6 @@
+ALTER TABLE transactions
+    DROP INDEX transactions_payment_reference_unique,
+    DROP COLUMN picked_up_at,
+    DROP COLUMN payment_reference,
+    DROP COLUMN payment_status,
+    DROP COLUMN payment_type;
@@ -0,0 +1,6 @@
+ALTER TABLE transactions
+    ADD COLUMN payment_type ENUM('cash', 'card') NOT NULL DEFAULT 'cash' AFTER amount,
+    ADD COLUMN payment_status ENUM('pending', 'authorized', 'captured', 'refunded', 'failed') NOT NULL DEFAULT 'pending' AFTER payment_type,
+    ADD COLUMN payment_reference VARCHAR(100) NULL AFTER payment_status,
+    ADD COLUMN picked_up_at DATETIME NULL AFTER purchase_day,
+    ADD UNIQUE KEY transactions_payment_reference_unique (payment_reference);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS partner_ratings;
+
+ALTER TABLE feedback
+    DROP FOREIGN KEY feedback_partner_id_fk,
+    DROP FOREIGN KEY feedback_user_id_fk;
+
+ALTER TABLE feedback
+    DROP INDEX feedback_transaction_unique,
+    DROP COLUMN partner_id,
+    DROP COLUMN user_id,
+    MODIFY rating INTEGER DEFAULT 0;
@@ -0,0 +1,41 @@
+ALTER TABLE feedback
+    ADD COLUMN user_id INTEGER NULL AFTER transaction_id,
+    ADD COLUMN partner_id INTEGER NULL AFTER user_id;
+
+UPDATE feedback f
+    JOIN transactions t ON t.id = f.transaction_id
+    SET f.user_id = t.user_id, f.partner_id = t.partner_id;
+
+ALTER TABLE feedback
+    MODIFY rating INTEGER NOT NULL,
+    MODIFY user_id INTEGER NOT NULL,
+    MODIFY partner_id INTEGER NOT NULL,
+    ADD CONSTRAINT feedback_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    ADD CONSTRAINT feedback_partner_id_fk FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE,
+    ADD UNIQUE KEY feedback_transaction_unique (transaction_id);
+
+CREATE TABLE IF NOT EXISTS partner_ratings (
+    partner_id INTEGER PRIMARY KEY,
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    rating_sum INTEGER NOT NULL DEFAULT 0,
+    rating_1 INTEGER NOT NULL DEFAULT 0,
+    rating_2 INTEGER NOT NULL DEFAULT 0,
+    rating_3 INTEGER NOT NULL DEFAULT 0,
+    rating_4 INTEGER NOT NULL DEFAULT 0,
+    rating_5 INTEGER NOT NULL DEFAULT 0,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+INSERT INTO partner_ratings (partner_id, rating_count, rating_sum, rating_1, rating_2, rating_3, rating_4, rating_5)
+SELECT partner_id,
+       COUNT(*),
+       SUM(rating),
+       SUM(rating = 1),
+       SUM(rating = 2),
+       SUM(rating = 3),
+       SUM(rating = 4),
+       SUM(rating = 5)
+FROM feedback
+WHERE rating BETWEEN 1 AND 5
+GROUP BY partner_id;
@@ -0,0 +1,6 @@
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens
+    DROP COLUMN locked_until,
+    DROP COLUMN attempts,
+    CHANGE COLUMN code_hash token VARCHAR(50);
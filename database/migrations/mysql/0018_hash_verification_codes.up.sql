@@ -0,0 +1,8 @@
+-- Codes were stored in plain text. They cannot be hashed after the fact, so
+-- outstanding codes are dropped and users ask for a new one.
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens
+    CHANGE COLUMN token code_hash CHAR(64) NOT NULL,
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0 AFTER code_hash,
+    ADD COLUMN locked_until DATETIME NULL AFTER attempts;
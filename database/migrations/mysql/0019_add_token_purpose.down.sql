@@ -0,0 +1,7 @@
+DELETE FROM user_tokens WHERE purpose <> 'verify_email';
+
+DROP INDEX user_tokens_user_purpose_idx ON user_tokens;
+
+ALTER TABLE user_tokens
+    DROP COLUMN purpose,
+    MODIFY COLUMN email VARCHAR(30) NOT NULL;
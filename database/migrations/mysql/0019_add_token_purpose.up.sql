@@ -0,0 +1,9 @@
+-- Codes now have a purpose, so a password reset code cannot verify an email
+-- and the reverse. The codes that are out were all sent to verify an email.
+-- The email is the address the code went to, which for an email change is the
+-- new one, so it gets the length of users.email.
+ALTER TABLE user_tokens
+    ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'verify_email' AFTER user_id,
+    MODIFY COLUMN email VARCHAR(255) NOT NULL;
+
+CREATE UNIQUE INDEX user_tokens_user_purpose_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE users
+    DROP COLUMN password_reset_required_at,
+    DROP COLUMN deleted_at,
+    DROP COLUMN suspended_at;
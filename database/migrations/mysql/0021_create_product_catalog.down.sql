@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS product_dietary_tags;
+DROP TABLE IF EXISTS product_allergens;
+
+ALTER TABLE products
+    DROP FOREIGN KEY products_partner_id_fk,
+    DROP INDEX products_partner_idx,
+    DROP COLUMN partner_id,
+    DROP COLUMN unit_weight_kg,
+    DROP COLUMN category,
+    MODIFY name VARCHAR(30) NOT NULL;
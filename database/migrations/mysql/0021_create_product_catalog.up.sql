@@ -0,0 +1,23 @@
+-- Products without a partner make up the shared catalog. The others belong
+-- to the partner that added them.
+ALTER TABLE products
+    MODIFY name VARCHAR(100) NOT NULL,
+    ADD COLUMN category VARCHAR(20) NOT NULL DEFAULT 'other' AFTER name,
+    ADD COLUMN unit_weight_kg DECIMAL(7, 3) NOT NULL DEFAULT 0 AFTER category,
+    ADD COLUMN partner_id INTEGER NULL AFTER unit_weight_kg,
+    ADD CONSTRAINT products_partner_id_fk FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE,
+    ADD INDEX products_partner_idx (partner_id);
+
+CREATE TABLE IF NOT EXISTS product_allergens (
+    product_id INTEGER NOT NULL,
+    allergen VARCHAR(20) NOT NULL,
+    PRIMARY KEY (product_id, allergen),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_dietary_tags (
+    product_id INTEGER NOT NULL,
+    tag VARCHAR(20) NOT NULL,
+    PRIMARY KEY (product_id, tag),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- Emails are unique regardless of case. The default collations already
+-- compare without case; this pins one in case the server is set up otherwise.
+-- MODIFY keeps the unique index on the column.
+ALTER TABLE users MODIFY email VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
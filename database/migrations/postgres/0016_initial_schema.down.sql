@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS email_outbox;
+DROP TABLE IF EXISTS partner_ratings;
+DROP TABLE IF EXISTS feedback;
+DROP TABLE IF EXISTS magic_bag_products;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS magic_bags;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS partners;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS set_date_updated();
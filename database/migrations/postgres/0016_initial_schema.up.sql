@@ -0,0 +1,188 @@
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    fullname VARCHAR(30) NOT NULL,
+    email VARCHAR(255) UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    phone_number VARCHAR(40) UNIQUE,
+    status VARCHAR(10) DEFAULT 'inactive' CHECK (status IN ('active', 'inactive')),
+    user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('waste_warrior', 'partners', 'admin')),
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    email VARCHAR(30) NOT NULL,
+    token VARCHAR(50) UNIQUE,
+    expire_at TIMESTAMPTZ NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expire_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS partners (
+    id SERIAL PRIMARY KEY,
+    business_number VARCHAR(30) NOT NULL,
+    user_id INTEGER NOT NULL UNIQUE,
+    logo VARCHAR(255) NULL,
+    address VARCHAR(255) NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    rejection_reason VARCHAR(255) NULL,
+    reviewed_at TIMESTAMPTZ NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(30) NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS magic_bags (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NULL,
+    bag_price DECIMAL(10, 2) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    pickup_start TIMESTAMPTZ NULL,
+    pickup_end TIMESTAMPTZ NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'withdrawn')),
+    partner_id INTEGER NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id SERIAL PRIMARY KEY,
+    amount DOUBLE PRECISION,
+    payment_type VARCHAR(10) NOT NULL DEFAULT 'cash' CHECK (payment_type IN ('cash', 'card')),
+    payment_status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (payment_status IN ('pending', 'authorized', 'captured', 'refunded', 'failed')),
+    payment_reference VARCHAR(100) NULL UNIQUE,
+    magic_bag_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    partner_id INTEGER NOT NULL,
+    purchase_day DATE NOT NULL,
+    picked_up_at TIMESTAMPTZ NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS transactions_daily_partner_idx ON transactions (user_id, partner_id, purchase_day);
+
+CREATE TABLE IF NOT EXISTS magic_bag_products (
+    id SERIAL PRIMARY KEY,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    magic_bag_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    UNIQUE (magic_bag_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+    id SERIAL PRIMARY KEY,
+    rating INTEGER NOT NULL,
+    comment TEXT NULL,
+    transaction_id INTEGER NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL,
+    partner_id INTEGER NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS partner_ratings (
+    partner_id INTEGER PRIMARY KEY,
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    rating_sum INTEGER NOT NULL DEFAULT 0,
+    rating_1 INTEGER NOT NULL DEFAULT 0,
+    rating_2 INTEGER NOT NULL DEFAULT 0,
+    rating_3 INTEGER NOT NULL DEFAULT 0,
+    rating_4 INTEGER NOT NULL DEFAULT 0,
+    rating_5 INTEGER NOT NULL DEFAULT 0,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id SERIAL PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    data TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'sent', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NULL,
+    last_error TEXT NULL,
+    sent_at TIMESTAMPTZ NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    date_updated TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (status, next_attempt_at);
+
+-- PostgreSQL has no ON UPDATE CURRENT_TIMESTAMP, so date_updated is kept
+-- current by a trigger per table.
+CREATE OR REPLACE FUNCTION set_date_updated() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.date_updated = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_date_updated BEFORE UPDATE ON users
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER user_tokens_date_updated BEFORE UPDATE ON user_tokens
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER refresh_tokens_date_updated BEFORE UPDATE ON refresh_tokens
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER partners_date_updated BEFORE UPDATE ON partners
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER products_date_updated BEFORE UPDATE ON products
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER magic_bags_date_updated BEFORE UPDATE ON magic_bags
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER transactions_date_updated BEFORE UPDATE ON transactions
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER magic_bag_products_date_updated BEFORE UPDATE ON magic_bag_products
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER feedback_date_updated BEFORE UPDATE ON feedback
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER partner_ratings_date_updated BEFORE UPDATE ON partner_ratings
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
+
+CREATE TRIGGER email_outbox_date_updated BEFORE UPDATE ON email_outbox
+FOR EACH ROW EXECUTE FUNCTION set_date_updated();
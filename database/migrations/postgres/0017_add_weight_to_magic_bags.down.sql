@@ -0,0 +1,2 @@
+ALTER TABLE magic_bags
+    DROP COLUMN weight_kg;
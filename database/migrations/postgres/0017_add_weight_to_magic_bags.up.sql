@@ -0,0 +1,2 @@
+ALTER TABLE magic_bags
+    ADD COLUMN weight_kg DECIMAL(6, 2) NOT NULL DEFAULT 0 CHECK (weight_kg >= 0);
@@ -0,0 +1,8 @@
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens
+    DROP COLUMN locked_until,
+    DROP COLUMN attempts,
+    ALTER COLUMN code_hash DROP NOT NULL,
+    ALTER COLUMN code_hash TYPE VARCHAR(50);
+ALTER TABLE user_tokens RENAME COLUMN code_hash TO token;
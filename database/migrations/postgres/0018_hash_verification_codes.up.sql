@@ -0,0 +1,10 @@
+-- Codes were stored in plain text. They cannot be hashed after the fact, so
+-- outstanding codes are dropped and users ask for a new one.
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens RENAME COLUMN token TO code_hash;
+ALTER TABLE user_tokens
+    ALTER COLUMN code_hash TYPE CHAR(64),
+    ALTER COLUMN code_hash SET NOT NULL,
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until TIMESTAMPTZ;
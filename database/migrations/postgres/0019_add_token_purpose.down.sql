@@ -0,0 +1,7 @@
+DELETE FROM user_tokens WHERE purpose <> 'verify_email';
+
+DROP INDEX IF EXISTS user_tokens_user_purpose_idx;
+
+ALTER TABLE user_tokens
+    DROP COLUMN purpose,
+    ALTER COLUMN email TYPE VARCHAR(30);
@@ -0,0 +1,21 @@
+ALTER TABLE users
+    ADD COLUMN suspended_at TIMESTAMPTZ NULL,
+    ADD COLUMN deleted_at TIMESTAMPTZ NULL,
+    ADD COLUMN password_reset_required_at TIMESTAMPTZ NULL;
+
+-- Every action an admin takes on a user account. Entries are never changed,
+-- and users are only ever soft deleted, so the references always hold.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL,
+    target_user_id INTEGER NOT NULL,
+    action VARCHAR(40) NOT NULL,
+    old_values TEXT NOT NULL,
+    new_values TEXT NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    date_created TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES users(id),
+    FOREIGN KEY (target_user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (target_user_id, date_created);
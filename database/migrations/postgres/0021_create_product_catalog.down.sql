@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS product_dietary_tags;
+DROP TABLE IF EXISTS product_allergens;
+
+DROP INDEX IF EXISTS products_partner_idx;
+
+ALTER TABLE products
+    DROP COLUMN partner_id,
+    DROP COLUMN unit_weight_kg,
+    DROP COLUMN category,
+    ALTER COLUMN name TYPE VARCHAR(30);
@@ -0,0 +1,23 @@
+-- Products without a partner make up the shared catalog. The others belong
+-- to the partner that added them.
+ALTER TABLE products
+    ALTER COLUMN name TYPE VARCHAR(100),
+    ADD COLUMN category VARCHAR(20) NOT NULL DEFAULT 'other',
+    ADD COLUMN unit_weight_kg DECIMAL(7, 3) NOT NULL DEFAULT 0 CHECK (unit_weight_kg >= 0),
+    ADD COLUMN partner_id INTEGER NULL REFERENCES partners(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS products_partner_idx ON products (partner_id);
+
+CREATE TABLE IF NOT EXISTS product_allergens (
+    product_id INTEGER NOT NULL,
+    allergen VARCHAR(20) NOT NULL,
+    PRIMARY KEY (product_id, allergen),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_dietary_tags (
+    product_id INTEGER NOT NULL,
+    tag VARCHAR(20) NOT NULL,
+    PRIMARY KEY (product_id, tag),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
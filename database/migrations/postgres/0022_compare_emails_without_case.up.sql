@@ -0,0 +1,3 @@
+-- Emails are unique regardless of case, as they are on MySQL. Queries compare
+-- LOWER(email) so they use this index.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (LOWER(email));
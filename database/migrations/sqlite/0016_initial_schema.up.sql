@@ -0,0 +1,216 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fullname VARCHAR(30) NOT NULL,
+    email VARCHAR(255) UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    phone_number VARCHAR(40) UNIQUE,
+    status VARCHAR(10) DEFAULT 'inactive' CHECK (status IN ('active', 'inactive')),
+    user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('waste_warrior', 'partners', 'admin')),
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    email VARCHAR(30) NOT NULL,
+    token VARCHAR(50) UNIQUE,
+    expire_at DATETIME NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expire_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS partners (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    business_number VARCHAR(30) NOT NULL,
+    user_id INTEGER NOT NULL UNIQUE,
+    logo VARCHAR(255) NULL,
+    address VARCHAR(255) NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    rejection_reason VARCHAR(255) NULL,
+    reviewed_at DATETIME NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(30) NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS magic_bags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NULL,
+    bag_price DECIMAL(10, 2) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    pickup_start DATETIME NULL,
+    pickup_end DATETIME NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'withdrawn')),
+    partner_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    amount FLOAT,
+    payment_type VARCHAR(10) NOT NULL DEFAULT 'cash' CHECK (payment_type IN ('cash', 'card')),
+    payment_status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (payment_status IN ('pending', 'authorized', 'captured', 'refunded', 'failed')),
+    payment_reference VARCHAR(100) NULL UNIQUE,
+    magic_bag_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    partner_id INTEGER NOT NULL,
+    purchase_day DATE NOT NULL,
+    picked_up_at DATETIME NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS transactions_daily_partner_idx ON transactions (user_id, partner_id, purchase_day);
+
+CREATE TABLE IF NOT EXISTS magic_bag_products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    magic_bag_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (magic_bag_id) REFERENCES magic_bags(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    UNIQUE (magic_bag_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rating INTEGER NOT NULL,
+    comment TEXT NULL,
+    transaction_id INTEGER NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL,
+    partner_id INTEGER NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS partner_ratings (
+    partner_id INTEGER PRIMARY KEY,
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    rating_sum INTEGER NOT NULL DEFAULT 0,
+    rating_1 INTEGER NOT NULL DEFAULT 0,
+    rating_2 INTEGER NOT NULL DEFAULT 0,
+    rating_3 INTEGER NOT NULL DEFAULT 0,
+    rating_4 INTEGER NOT NULL DEFAULT 0,
+    rating_5 INTEGER NOT NULL DEFAULT 0,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    data TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'sent', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    locked_until DATETIME NULL,
+    last_error TEXT NULL,
+    sent_at DATETIME NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (status, next_attempt_at);
+
+-- SQLite has no ON UPDATE CURRENT_TIMESTAMP, so date_updated is kept
+-- current by a trigger per table. The WHEN clause lets an UPDATE that sets
+-- date_updated itself win and stops the trigger from re-firing.
+
+CREATE TRIGGER IF NOT EXISTS users_date_updated AFTER UPDATE ON users
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE users SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_tokens_date_updated AFTER UPDATE ON user_tokens
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE user_tokens SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS refresh_tokens_date_updated AFTER UPDATE ON refresh_tokens
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE refresh_tokens SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS partners_date_updated AFTER UPDATE ON partners
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE partners SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS products_date_updated AFTER UPDATE ON products
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE products SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS magic_bags_date_updated AFTER UPDATE ON magic_bags
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE magic_bags SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS transactions_date_updated AFTER UPDATE ON transactions
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE transactions SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS magic_bag_products_date_updated AFTER UPDATE ON magic_bag_products
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE magic_bag_products SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS feedback_date_updated AFTER UPDATE ON feedback
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE feedback SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS email_outbox_date_updated AFTER UPDATE ON email_outbox
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE email_outbox SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS partner_ratings_date_updated AFTER UPDATE ON partner_ratings
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE partner_ratings SET date_updated = CURRENT_TIMESTAMP WHERE partner_id = NEW.partner_id;
+END;
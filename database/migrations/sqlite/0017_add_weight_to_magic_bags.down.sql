@@ -0,0 +1 @@
+ALTER TABLE magic_bags DROP COLUMN weight_kg;
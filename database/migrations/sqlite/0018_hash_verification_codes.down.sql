@@ -0,0 +1,5 @@
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens DROP COLUMN locked_until;
+ALTER TABLE user_tokens DROP COLUMN attempts;
+ALTER TABLE user_tokens RENAME COLUMN code_hash TO token;
@@ -0,0 +1,7 @@
+-- Codes were stored in plain text. They cannot be hashed after the fact, so
+-- outstanding codes are dropped and users ask for a new one.
+DELETE FROM user_tokens;
+
+ALTER TABLE user_tokens RENAME COLUMN token TO code_hash;
+ALTER TABLE user_tokens ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_tokens ADD COLUMN locked_until DATETIME;
@@ -0,0 +1,5 @@
+DELETE FROM user_tokens WHERE purpose <> 'verify_email';
+
+DROP INDEX IF EXISTS user_tokens_user_purpose_idx;
+
+ALTER TABLE user_tokens DROP COLUMN purpose;
@@ -0,0 +1,7 @@
+-- Codes now have a purpose, so a password reset code cannot verify an email
+-- and the reverse. The codes that are out were all sent to verify an email.
+-- SQLite does not enforce the length of email, which for an email change
+-- holds the new address, so unlike the other databases it is left as is.
+ALTER TABLE user_tokens ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'verify_email';
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_tokens_user_purpose_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE users DROP COLUMN password_reset_required_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN suspended_at;
@@ -0,0 +1,31 @@
+DROP TABLE IF EXISTS product_dietary_tags;
+DROP TABLE IF EXISTS product_allergens;
+
+DROP INDEX IF EXISTS products_partner_idx;
+
+-- SQLite cannot drop a column with a foreign key, so products is rebuilt.
+-- Dropping the old table cascades to the bag items, which are put back
+-- afterwards.
+CREATE TEMP TABLE saved_magic_bag_products AS SELECT * FROM magic_bag_products;
+
+CREATE TABLE products_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(30) NOT NULL,
+    date_created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    date_updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO products_old (id, name, date_created, date_updated)
+    SELECT id, name, date_created, date_updated FROM products;
+
+DROP TABLE products;
+ALTER TABLE products_old RENAME TO products;
+
+INSERT INTO magic_bag_products SELECT * FROM saved_magic_bag_products;
+DROP TABLE saved_magic_bag_products;
+
+CREATE TRIGGER IF NOT EXISTS products_date_updated AFTER UPDATE ON products
+FOR EACH ROW WHEN NEW.date_updated IS OLD.date_updated
+BEGIN
+    UPDATE products SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
@@ -0,0 +1,4 @@
+-- Emails are unique regardless of case, as they are on MySQL. The column
+-- keeps its own unique index, since SQLite cannot drop it without rebuilding
+-- users; queries compare with COLLATE NOCASE so they use this one.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_nocase_idx ON users (email COLLATE NOCASE);
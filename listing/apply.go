@@ -0,0 +1,150 @@
+package listing
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Apply filters, sorts and pages rows the way the SQL stores do, for stores
+// that keep their rows in memory.
+func Apply[T any](rows []T, q Query, spec Spec[T]) ([]T, Page) {
+	kept := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if matches(row, q.Filters, spec) {
+			kept = append(kept, row)
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return compareRow(kept[i], sortValues(kept[j], q.Sort, spec), q.Sort, spec) < 0
+	})
+
+	start := q.Offset
+	if q.After != nil {
+		start = sort.Search(len(kept), func(i int) bool {
+			return compareRow(kept[i], q.After, q.Sort, spec) > 0
+		})
+	}
+	start = min(start, len(kept))
+	end := min(start+q.Limit, len(kept))
+
+	page := Page{Total: len(kept)}
+	if end < len(kept) && end > start {
+		page.NextCursor = spec.Cursor(q, kept[end-1])
+	}
+	return kept[start:end], page
+}
+
+func matches[T any](row T, filters []Filter, spec Spec[T]) bool {
+	for _, f := range filters {
+		value := spec.Fields[f.Field].Value(row)
+		if value == nil {
+			return false
+		}
+
+		if set, isSet := value.([]string); isSet {
+			if !matchesSet(set, f) {
+				return false
+			}
+			continue
+		}
+
+		ok := false
+		for _, want := range f.Values {
+			c := compare(value, want)
+			switch f.Operator {
+			case Contains:
+				ok = strings.Contains(strings.ToLower(value.(string)), strings.ToLower(want.(string)))
+			case Eq, In:
+				ok = c == 0
+			case Ne:
+				ok = c != 0
+			case Lt:
+				ok = c < 0
+			case Lte:
+				ok = c <= 0
+			case Gt:
+				ok = c > 0
+			case Gte:
+				ok = c >= 0
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSet checks a set field against an All or None filter.
+func matchesSet(set []string, f Filter) bool {
+	for _, want := range f.Values {
+		if slices.Contains(set, want.(string)) != (f.Operator == All) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortValues[T any](row T, sorts []Sort, spec Spec[T]) []any {
+	values := make([]any, len(sorts))
+	for i, s := range sorts {
+		values[i] = spec.Fields[s.Field].Value(row)
+	}
+	return values
+}
+
+// compareRow orders row against the sort values of another row, returning a
+// negative number when row comes first.
+func compareRow[T any](row T, values []any, sorts []Sort, spec Spec[T]) int {
+	for i, s := range sorts {
+		c := compare(spec.Fields[s.Field].Value(row), values[i])
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compare orders two values of the same kind.
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case int64:
+		b := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case bool:
+		b := b.(bool)
+		switch {
+		case !a && b:
+			return -1
+		case a && !b:
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package listing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// cursor is what an encoded cursor holds: the order it was made for and the
+// values of the last row of the page in that order.
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// Cursor returns the cursor of the page that follows row in the order of q.
+func (s Spec[T]) Cursor(q Query, row T) string {
+	c := cursor{Sort: sortKey(q.Sort)}
+	for _, sort := range q.Sort {
+		value := s.Fields[sort.Field].Value(row)
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		c.Values = append(c.Values, value)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor[T any](encoded string, sorts []Sort, spec Spec[T]) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&c)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sort != sortKey(sorts) || len(c.Values) != len(sorts) {
+		return nil, errors.New("cursor was made for another order")
+	}
+
+	after := make([]any, len(sorts))
+	for i, sort := range sorts {
+		after[i], err = cursorValue(spec.Fields[sort.Field].Kind, c.Values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return after, nil
+}
+
+func cursorValue(kind Kind, raw any) (any, error) {
+	switch v := raw.(type) {
+	case json.Number:
+		switch kind {
+		case Int:
+			return v.Int64()
+		case Float:
+			return v.Float64()
+		}
+	case bool:
+		if kind == Bool {
+			return v, nil
+		}
+	case string:
+		switch kind {
+		case String:
+			return v, nil
+		case Time:
+			return time.Parse(time.RFC3339Nano, v)
+		}
+	}
+	return nil, errors.New("cursor value does not match its field")
+}
+
+// sortKey writes an order the way the sort parameter does.
+func sortKey(sorts []Sort) string {
+	names := make([]string, len(sorts))
+	for i, s := range sorts {
+		names[i] = s.Field
+		if s.Desc {
+			names[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(names, ",")
+}
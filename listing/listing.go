@@ -0,0 +1,310 @@
+// Package listing parses the query string of list endpoints into a Query:
+// which rows to keep, in which order and which page of them to return.
+//
+//	GET /v1/magic-bags?bag_price[lte]=5&partner_id[in]=3,7&sort=-bag_price&limit=10
+//
+// Every list declares the fields clients may filter and sort on in a Spec and
+// anything else is rejected, so the stores only ever see whitelisted fields.
+//
+// A page is picked either with offset or with the next_cursor returned with
+// the previous page. A cursor resumes after the last row the client saw, so
+// rows added or removed in the meantime do not shift the pages the way they
+// do with offsets.
+package listing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+
+	// maxInValues caps the values of an in filter.
+	maxInValues = 50
+)
+
+// Kind is the type of a field, which decides how its filter values are parsed
+// and compared.
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Float
+	Time
+	Bool
+)
+
+type Operator string
+
+const (
+	Eq  Operator = "eq"
+	Ne  Operator = "ne"
+	Lt  Operator = "lt"
+	Lte Operator = "lte"
+	Gt  Operator = "gt"
+	Gte Operator = "gte"
+	In  Operator = "in"
+	// Contains matches strings holding the value, ignoring case.
+	Contains Operator = "contains"
+	// All and None filter set fields: the set must hold every one of the
+	// listed values, or none of them.
+	All  Operator = "all"
+	None Operator = "none"
+)
+
+var (
+	// Equality is the operator set for ids and enumerations.
+	Equality = []Operator{Eq, Ne, In}
+	// Ordered is the operator set for numbers, times and days.
+	Ordered = []Operator{Eq, Ne, Lt, Lte, Gt, Gte, In}
+	// Text is the operator set for free text searched by clients.
+	Text = []Operator{Eq, Contains}
+)
+
+// Field is a field of T that clients may filter or sort on.
+type Field[T any] struct {
+	Kind Kind
+	// Operators are the filters allowed on the field. A field without any
+	// cannot be filtered on.
+	Operators []Operator
+	// Values, when set, are the only values a string filter accepts.
+	Values   []string
+	Sortable bool
+	// Value reads the field from a row as a string, int64, float64, bool or
+	// time.Time, or nil when it is not set. Sortable fields are always set.
+	// Set fields, which are filtered with All or None, read as a []string.
+	Value func(T) any
+}
+
+// Spec lists the fields of one list. It must have a sortable "id" field,
+// which is unique and breaks ties between rows.
+type Spec[T any] struct {
+	Fields map[string]Field[T]
+	// Sort is the order used when a request does not give one.
+	Sort []Sort
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+type Filter struct {
+	Field    string
+	Operator Operator
+	// Values holds one value, or one per listed value for In, All and None,
+	// of the type Field.Value returns.
+	Values []any
+}
+
+type Query struct {
+	Filters []Filter
+	// Sort always ends with the id, so that no two rows tie.
+	Sort   []Sort
+	Limit  int
+	Offset int
+	// After holds the values of the Sort fields of the last row of the
+	// previous page when paging by cursor.
+	After []any
+}
+
+// Page is what a store reports next to the rows of a page.
+type Page struct {
+	// Total counts the rows matching the filters, across all pages.
+	Total int
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// Parse reads limit, offset, cursor, sort and the filters from the query
+// string. A filter is written field=value or field[operator]=value, with the
+// values of in, all and none separated by commas. Invalid parameters are added to v.
+func Parse[T any](v *validator.Validator, values url.Values, spec Spec[T]) Query {
+	q := Query{Limit: DefaultLimit}
+	var cursor string
+	sorted := false
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if len(values[key]) > 1 {
+			v.AddError(key, "must only be given once")
+			continue
+		}
+		value := values[key][0]
+
+		switch key {
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			v.Check(err == nil && limit >= 1 && limit <= MaxLimit, key, fmt.Sprintf("must be between 1 and %d", MaxLimit))
+			q.Limit = limit
+		case "offset":
+			offset, err := strconv.Atoi(value)
+			v.Check(err == nil && offset >= 0, key, "must be zero or more")
+			q.Offset = offset
+		case "cursor":
+			cursor = value
+		case "sort":
+			q.Sort = parseSort(v, value, spec)
+			sorted = true
+		default:
+			if filter, ok := parseFilter(v, key, value, spec); ok {
+				q.Filters = append(q.Filters, filter)
+			}
+		}
+	}
+
+	if !sorted {
+		q.Sort = append([]Sort{}, spec.Sort...)
+	}
+	q.Sort = withID(q.Sort)
+
+	if cursor != "" {
+		v.Check(q.Offset == 0, "cursor", "cannot be combined with offset")
+		after, err := decodeCursor(cursor, q.Sort, spec)
+		v.Check(err == nil, "cursor", "is not a cursor of this list and order")
+		q.After = after
+	}
+
+	return q
+}
+
+func parseSort[T any](v *validator.Validator, value string, spec Spec[T]) []Sort {
+	var sorts []Sort
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(value, ",") {
+		var s Sort
+		s.Field = strings.TrimSpace(name)
+		if strings.HasPrefix(s.Field, "-") {
+			s.Field = s.Field[1:]
+			s.Desc = true
+		}
+
+		field, ok := spec.Fields[s.Field]
+		if !ok || !field.Sortable {
+			v.AddError("sort", fmt.Sprintf("cannot sort by %q", s.Field))
+			continue
+		}
+		if seen[s.Field] {
+			v.AddError("sort", fmt.Sprintf("lists %q more than once", s.Field))
+			continue
+		}
+		seen[s.Field] = true
+		sorts = append(sorts, s)
+	}
+
+	return sorts
+}
+
+// withID adds the id to the order, in the direction of the last field, unless
+// the order already has it.
+func withID(sorts []Sort) []Sort {
+	desc := false
+	for _, s := range sorts {
+		if s.Field == "id" {
+			return sorts
+		}
+		desc = s.Desc
+	}
+	return append(sorts, Sort{Field: "id", Desc: desc})
+}
+
+func parseFilter[T any](v *validator.Validator, key, value string, spec Spec[T]) (Filter, bool) {
+	filter := Filter{Field: key, Operator: Eq}
+	if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+		filter.Field = key[:i]
+		filter.Operator = Operator(key[i+1 : len(key)-1])
+	}
+
+	field, ok := spec.Fields[filter.Field]
+	if !ok || len(field.Operators) == 0 {
+		v.AddError(key, "is not a filter of this list")
+		return filter, false
+	}
+	if !hasOperator(field.Operators, filter.Operator) {
+		v.AddError(key, fmt.Sprintf("does not support the %q operator", filter.Operator))
+		return filter, false
+	}
+
+	raw := []string{value}
+	if filter.Operator == In || filter.Operator == All || filter.Operator == None {
+		raw = strings.Split(value, ",")
+		if len(raw) > maxInValues {
+			v.AddError(key, fmt.Sprintf("must not list more than %d values", maxInValues))
+			return filter, false
+		}
+	}
+
+	for _, s := range raw {
+		s = strings.TrimSpace(s)
+		parsed, err := parseValue(field.Kind, s)
+		if err != nil {
+			v.AddError(key, err.Error())
+			return filter, false
+		}
+		if len(field.Values) > 0 && !validator.In(s, field.Values...) {
+			v.AddError(key, "must be one of "+strings.Join(field.Values, ", "))
+			return filter, false
+		}
+		filter.Values = append(filter.Values, parsed)
+	}
+
+	return filter, true
+}
+
+func hasOperator(operators []Operator, op Operator) bool {
+	for _, o := range operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValue reads a filter value. Times are RFC 3339 or a bare date, which
+// means midnight UTC.
+func parseValue(kind Kind, s string) (any, error) {
+	switch kind {
+	case Int:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return n, nil
+	case Float:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return f, nil
+	case Time:
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, s); err == nil {
+			return t, nil
+		}
+		return nil, errors.New("must be an RFC 3339 time or a date")
+	case Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	return s, nil
+}
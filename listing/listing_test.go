@@ -0,0 +1,205 @@
+package listing
+
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bag struct {
+	id      int64
+	title   string
+	price   float64
+	created time.Time
+	tags    []string
+}
+
+var bagSpec = Spec[bag]{
+	Fields: map[string]Field[bag]{
+		"id":         {Kind: Int, Operators: Ordered, Sortable: true, Value: func(b bag) any { return b.id }},
+		"title":      {Kind: String, Operators: Text, Value: func(b bag) any { return b.title }},
+		"price":      {Kind: Float, Operators: Ordered, Sortable: true, Value: func(b bag) any { return b.price }},
+		"created_at": {Kind: Time, Operators: Ordered, Sortable: true, Value: func(b bag) any { return b.created }},
+		"tags":       {Kind: String, Operators: []Operator{All, None}, Values: []string{"vegan", "bread", "dairy"}, Value: func(b bag) any { return b.tags }},
+	},
+	Sort: []Sort{{Field: "created_at", Desc: true}},
+}
+
+func parse(t *testing.T, query string) (Query, map[string]string) {
+	t.Helper()
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q): %v", query, err)
+	}
+	v := validator.New()
+	q := Parse(v, values, bagSpec)
+	return q, v.Errors
+}
+
+func TestParse(t *testing.T) {
+	q, errs := parse(t, "price[lte]=5&id[in]=3,7&sort=-price&limit=10")
+	if len(errs) != 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+
+	want := Query{
+		Filters: []Filter{
+			{Field: "id", Operator: In, Values: []any{int64(3), int64(7)}},
+			{Field: "price", Operator: Lte, Values: []any{5.0}},
+		},
+		Sort:  []Sort{{Field: "price", Desc: true}, {Field: "id", Desc: true}},
+		Limit: 10,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Fatalf("got %+v, want %+v", q, want)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	q, errs := parse(t, "")
+	if len(errs) != 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+	if q.Limit != DefaultLimit {
+		t.Fatalf("got limit %d, want %d", q.Limit, DefaultLimit)
+	}
+	want := []Sort{{Field: "created_at", Desc: true}, {Field: "id", Desc: true}}
+	if !reflect.DeepEqual(q.Sort, want) {
+		t.Fatalf("got sort %+v, want %+v", q.Sort, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"limit=0", "limit"},
+		{"limit=101", "limit"},
+		{"offset=-1", "offset"},
+		{"limit=1&limit=2", "limit"},
+		{"status=active", "status"},
+		{"title[lt]=a", "title[lt]"},
+		{"price=cheap", "price"},
+		{"created_at[gt]=yesterday", "created_at[gt]"},
+		{"tags[all]=meat", "tags[all]"},
+		{"sort=title", "sort"},
+		{"sort=price,-price", "sort"},
+		{"cursor=not-a-cursor", "cursor"},
+	}
+
+	for _, tt := range tests {
+		_, errs := parse(t, tt.query)
+		if _, ok := errs[tt.field]; !ok {
+			t.Errorf("%s: got errors %v, want one for %s", tt.query, errs, tt.field)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []bag{
+		{id: 1, title: "Bread box", price: 3, created: day, tags: []string{"bread", "vegan"}},
+		{id: 2, title: "Cheese plate", price: 6, created: day.Add(time.Hour), tags: []string{"dairy"}},
+		{id: 3, title: "Sourdough", price: 4, created: day.Add(2 * time.Hour), tags: []string{"bread"}},
+		{id: 4, title: "Salad", price: 4, created: day.Add(3 * time.Hour), tags: []string{"vegan"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"", []int64{4, 3, 2, 1}},
+		{"sort=price", []int64{1, 3, 4, 2}},
+		{"price[gte]=4&sort=-price", []int64{2, 4, 3}},
+		{"title[contains]=BREAD", []int64{1}},
+		{"id[ne]=2&sort=id", []int64{1, 3, 4}},
+		{"tags[all]=bread,vegan", []int64{1}},
+		{"tags[none]=bread&sort=id", []int64{2, 4}},
+		{"created_at[lt]=2024-01-01T02:00:00Z&sort=created_at", []int64{1, 2}},
+		{"sort=id&limit=2&offset=1", []int64{2, 3}},
+	}
+
+	for _, tt := range tests {
+		q, errs := parse(t, tt.query)
+		if len(errs) != 0 {
+			t.Fatalf("%s: got errors %v", tt.query, errs)
+		}
+		got, _ := Apply(rows, q, bagSpec)
+		ids := make([]int64, len(got))
+		for i, row := range got {
+			ids[i] = row.id
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rows []bag
+	for i := int64(1); i <= 5; i++ {
+		// Every two bags share a price and a time, so the id breaks the tie.
+		rows = append(rows, bag{id: i, price: float64(i / 2), created: day.Add(time.Duration(i/2) * time.Minute)})
+	}
+
+	for _, order := range []string{"price", "-price", "created_at", "-created_at,price"} {
+		q, errs := parse(t, "sort="+order+"&limit=2")
+		if len(errs) != 0 {
+			t.Fatalf("%s: got errors %v", order, errs)
+		}
+		all, _ := Apply(rows, Query{Sort: q.Sort, Limit: MaxLimit}, bagSpec)
+
+		var paged []bag
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(rows) {
+				t.Fatalf("%s: the cursor does not move on", order)
+			}
+			query := "sort=" + order + "&limit=2"
+			if cursor != "" {
+				query += "&cursor=" + cursor
+			}
+			q, errs := parse(t, query)
+			if len(errs) != 0 {
+				t.Fatalf("%s: got errors %v", query, errs)
+			}
+			page, info := Apply(rows, q, bagSpec)
+			if info.Total != len(rows) {
+				t.Fatalf("%s: got total %d, want %d", query, info.Total, len(rows))
+			}
+			paged = append(paged, page...)
+			if info.NextCursor == "" {
+				break
+			}
+			cursor = info.NextCursor
+		}
+
+		if !reflect.DeepEqual(paged, all) {
+			t.Errorf("%s: paging gave %v, want %v", order, paged, all)
+		}
+	}
+}
+
+func TestCursorOtherOrder(t *testing.T) {
+	row := bag{id: 1, price: 2, created: time.Now()}
+	q, _ := parse(t, "sort=price")
+	cursor := bagSpec.Cursor(q, row)
+
+	_, errs := parse(t, "sort=-price&cursor="+cursor)
+	if _, ok := errs["cursor"]; !ok {
+		t.Fatalf("got errors %v, want one for a cursor of another order", errs)
+	}
+	_, errs = parse(t, "sort=price&offset=2&cursor="+cursor)
+	if _, ok := errs["cursor"]; !ok {
+		t.Fatalf("got errors %v, want one for a cursor with an offset", errs)
+	}
+	_, errs = parse(t, "sort=price&cursor="+cursor)
+	if len(errs) != 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import "sync"
+
+// SentEmail is a message recorded by CaptureSender.
+type SentEmail struct {
+	Recipient string
+	Template  string
+	Data      interface{}
+	Message   *Message
+}
+
+// CaptureSender keeps every email in memory so tests can assert which
+// templates were sent to whom with what data. Templates are still rendered,
+// so a broken template fails the same way it would with SMTP.
+type CaptureSender struct {
+	mu   sync.Mutex
+	sent []SentEmail
+}
+
+func NewCaptureSender() *CaptureSender {
+	return &CaptureSender{}
+}
+
+func (c *CaptureSender) Send(recipient string, templateFile string, data interface{}) error {
+	rendered, err := Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = append(c.sent, SentEmail{
+		Recipient: recipient,
+		Template:  templateFile,
+		Data:      data,
+		Message:   rendered,
+	})
+	return nil
+}
+
+// Sent returns a copy of every captured email in the order they were sent.
+func (c *CaptureSender) Sent() []SentEmail {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]SentEmail(nil), c.sent...)
+}
+
+// SentTo returns the captured emails addressed to recipient.
+func (c *CaptureSender) SentTo(recipient string) []SentEmail {
+	var emails []SentEmail
+	for _, email := range c.Sent() {
+		if email.Recipient == recipient {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// Reset forgets every captured email.
+func (c *CaptureSender) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = nil
+}
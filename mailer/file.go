@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender writes every email into a maildir instead of sending it, so
+// messages can be opened with a mail client during local development.
+// Messages are written to tmp/ and then moved into new/ once complete.
+type FileSender struct {
+	dir    string
+	sender string
+	seq    atomic.Uint64
+}
+
+func NewFileSender(dir, sender string) (*FileSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileSender{dir: dir, sender: sender}, nil
+}
+
+func (f *FileSender) Send(recipient string, templateFile string, data interface{}) error {
+	rendered, err := Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s.eml", time.Now().UnixNano(), os.Getpid(), f.seq.Add(1), hostname)
+	tmpPath := filepath.Join(f.dir, "tmp", name)
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = newMessage(f.sender, recipient, rendered).WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(f.dir, "new", name))
+}
+
+// Ping checks that the maildir can still be written to.
+func (f *FileSender) Ping(ctx context.Context) error {
+	file, err := os.CreateTemp(filepath.Join(f.dir, "tmp"), "ping-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
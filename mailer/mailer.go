@@ -2,62 +2,82 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"github.com/go-mail/mail/v2"
 	"html/template"
+	"net"
+	"strconv"
 	"time"
 )
 
 //go:embed "templates"
 var templateFS embed.FS
 
-// Define a Mailer struct which contains a mail.Dialer instance (used to connect to a
-// SMTP server) and the sender information for your emails (the name and address you
-// want the email to be from, such as "Abah Joseph <me@abahjosep.com>").
-type Mailer struct {
-	dialer *mail.Dialer
-	sender string
+// Sender is implemented by every mail backend. The template file names one of
+// the files in mailer/templates, which must define the "subject",
+// "plainBody" and "htmlBody" templates.
+type Sender interface {
+	Send(recipient string, templateFile string, data interface{}) error
 }
 
-func New(host string, port int, username, password, sender string) Mailer {
-
-	dialer := mail.NewDialer(host, port, username, password)
-	dialer.Timeout = 5 * time.Second
+// Pinger is implemented by backends that can check they are able to deliver
+// email without sending any.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
 
-	return Mailer{
-		dialer: dialer,
-		sender: sender,
+// Ping checks the backend behind sender. Senders that cannot be checked are
+// assumed to work.
+func Ping(ctx context.Context, sender Sender) error {
+	if p, ok := sender.(Pinger); ok {
+		return p.Ping(ctx)
 	}
+	return nil
 }
 
-// Define a Send() method on the Mailer type. This takes the recipient email address
-// as the first parameter, the name of the file containing the templates, and any
-// dynamic data for the templates as an interface{} parameter.
-func (m Mailer) Send(recipient string, templateFile string, data interface{}) error {
+// Message is a rendered email template.
+type Message struct {
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
 
+// Render executes the subject, plain text and HTML templates from the given
+// template file.
+func Render(templateFile string, data interface{}) (*Message, error) {
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	subject := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	plainBody := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	htmlBody := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return &Message{
+		Subject:   subject.String(),
+		PlainBody: plainBody.String(),
+		HTMLBody:  htmlBody.String(),
+	}, nil
+}
+
+// newMessage builds the MIME message shared by the SMTP and file backends.
+func newMessage(sender, recipient string, m *Message) *mail.Message {
 	// Use the mail.NewMessage() function to initialize a new mail.Message instance.
 	// Then we use the SetHeader() method to set the email recipient, sender and subject
 	// headers, the SetBody() method to set the plain-text body, and the AddAlternative()
@@ -65,10 +85,50 @@ func (m Mailer) Send(recipient string, templateFile string, data interface{}) er
 	// always be called *after* SetBody().
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipient)
-	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
+	msg.SetHeader("From", sender)
+	msg.SetHeader("Subject", m.Subject)
+	msg.SetBody("text/plain", m.PlainBody)
+	msg.AddAlternative("text/html", m.HTMLBody)
+	return msg
+}
+
+// Define a Mailer struct which contains a mail.Dialer instance (used to connect to a
+// SMTP server) and the sender information for your emails (the name and address you
+// want the email to be from, such as "Abah Joseph <me@abahjosep.com>").
+type Mailer struct {
+	dialer *mail.Dialer
+	sender string
+}
+
+func New(host string, port int, username, password, sender string) Mailer {
 
-	return m.dialer.DialAndSend(msg)
+	dialer := mail.NewDialer(host, port, username, password)
+	dialer.Timeout = 5 * time.Second
+
+	return Mailer{
+		dialer: dialer,
+		sender: sender,
+	}
+}
+
+// Define a Send() method on the Mailer type. This takes the recipient email address
+// as the first parameter, the name of the file containing the templates, and any
+// dynamic data for the templates as an interface{} parameter.
+func (m Mailer) Send(recipient string, templateFile string, data interface{}) error {
+	rendered, err := Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	return m.dialer.DialAndSend(newMessage(m.sender, recipient, rendered))
+}
+
+// Ping opens a connection to the SMTP server without sending anything.
+func (m Mailer) Ping(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.dialer.Host, strconv.Itoa(m.dialer.Port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
 }
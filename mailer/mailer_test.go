@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var tokenData = map[string]interface{}{
+	"Code":     "123456",
+	"userName": "Jane Doe",
+	"email":    "jane@example.com",
+	"ExpireAt": "12:00",
+}
+
+func TestCaptureSender(t *testing.T) {
+	capture := NewCaptureSender()
+
+	err := capture.Send("jane@example.com", "user_token.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	err = capture.Send("john@example.com", "password_reset.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	sent := capture.Sent()
+	if len(sent) != 2 || sent[0].Recipient != "jane@example.com" || sent[1].Recipient != "john@example.com" {
+		t.Fatalf("got %+v, want the two emails in order", sent)
+	}
+
+	jane := capture.SentTo("jane@example.com")
+	if len(jane) != 1 || jane[0].Template != "user_token.html" {
+		t.Fatalf("got %+v, want the user_token.html sent to jane", jane)
+	}
+	if jane[0].Message.Subject != "Welcome to TestTeam!" {
+		t.Fatalf("got subject %q", jane[0].Message.Subject)
+	}
+	if !strings.Contains(jane[0].Message.PlainBody, "123456") || !strings.Contains(jane[0].Message.HTMLBody, "123456") {
+		t.Fatal("the rendered bodies do not hold the code")
+	}
+
+	// Changing the returned slice does not change what was captured.
+	sent[0].Recipient = "someone@example.com"
+	if len(capture.SentTo("jane@example.com")) != 1 {
+		t.Fatal("Sent returned the captured emails themselves")
+	}
+
+	capture.Reset()
+	if len(capture.Sent()) != 0 {
+		t.Fatal("Reset kept emails")
+	}
+}
+
+func TestCaptureSenderBrokenTemplate(t *testing.T) {
+	capture := NewCaptureSender()
+
+	err := capture.Send("jane@example.com", "missing.html", tokenData)
+	if err == nil {
+		t.Fatal("sending a missing template succeeded")
+	}
+	if len(capture.Sent()) != 0 {
+		t.Fatal("an email that failed to render was captured")
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender, err := NewFileSender(dir, "TestTeam <no-reply@example.com>")
+	if err != nil {
+		t.Fatalf("NewFileSender: %v", err)
+	}
+
+	err = sender.Send("jane@example.com", "user_token.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files in new/, want 1", len(files))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(data), "To: jane@example.com") {
+		t.Fatalf("message has no To header:\n%s", data)
+	}
+
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Fatalf("left %d files in tmp/", len(tmp))
+	}
+}
@@ -0,0 +1,299 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OutboxConfig tunes the outbox workers. Zero values fall back to the
+// defaults in NewOutbox.
+type OutboxConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a worker may hold a job before another worker
+	// assumes it crashed and picks the job up again.
+	Lease time.Duration
+}
+
+// Outbox is a Sender that persists every email in the email_outbox table and
+// delivers it from a pool of workers through the wrapped Sender. Failed
+// deliveries are retried with exponential backoff until MaxAttempts, after
+// which the job is kept as a dead letter. The template data, which can hold
+// the codes emailed to users, is cleared once a job is sent or dead, so only
+// the recipient, template and outcome of finished jobs are kept.
+type Outbox struct {
+	db     *database.DB
+	sender Sender
+	logger *slog.Logger
+	config OutboxConfig
+
+	// sent and failed count delivery attempts since the process started.
+	sent   atomic.Uint64
+	failed atomic.Uint64
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewOutbox(db *database.DB, sender Sender, logger *slog.Logger, config OutboxConfig) *Outbox {
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.Lease <= 0 {
+		config.Lease = 2 * time.Minute
+	}
+
+	return &Outbox{
+		db:     db,
+		sender: sender,
+		logger: logger,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Send queues the email. The data is stored as JSON, so it should only hold
+// values that survive a round trip through encoding/json.
+func (o *Outbox) Send(recipient string, templateFile string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO email_outbox (recipient, template, data, next_attempt_at)
+	VALUES (?, ?, ?, ?)
+	`
+	_, err = o.db.Exec(query, recipient, templateFile, string(payload), time.Now())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start launches the workers. They run until Shutdown is called.
+func (o *Outbox) Start() {
+	for i := 0; i < o.config.Workers; i++ {
+		o.wg.Add(1)
+		go o.work()
+	}
+}
+
+// Shutdown stops the workers from claiming new jobs and waits for the jobs in
+// flight to finish, or for ctx to expire. Jobs that were still queued are
+// picked up again on the next start.
+func (o *Outbox) Shutdown(ctx context.Context) error {
+	o.stopOnce.Do(func() { close(o.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (o *Outbox) work() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every due job before going back to sleep.
+		for {
+			select {
+			case <-o.stop:
+				return
+			default:
+			}
+
+			processed, err := o.processNext()
+			if err != nil {
+				o.logger.Error("email outbox failed", "error", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+type outboxJob struct {
+	id        int64
+	recipient string
+	template  string
+	data      string
+	attempts  int
+}
+
+// processNext claims one due job and tries to deliver it. It reports whether
+// a job was found.
+func (o *Outbox) processNext() (bool, error) {
+	job, err := o.claim()
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	var data map[string]interface{}
+	err = json.Unmarshal([]byte(job.data), &data)
+	if err == nil {
+		err = o.sender.Send(job.recipient, job.template, data)
+	}
+
+	if err == nil {
+		o.sent.Add(1)
+		_, err = o.db.Exec("UPDATE email_outbox SET status = 'sent', data = '{}', sent_at = ?, locked_until = NULL, last_error = NULL WHERE id = ?", time.Now(), job.id)
+		return true, err
+	}
+
+	o.failed.Add(1)
+	if job.attempts >= o.config.MaxAttempts {
+		o.logger.Warn("email given up after the last attempt", "job_id", job.id, "template", job.template, "attempts", job.attempts, "error", err)
+		_, dbErr := o.db.Exec("UPDATE email_outbox SET status = 'dead', data = '{}', locked_until = NULL, last_error = ? WHERE id = ?", err.Error(), job.id)
+		return true, dbErr
+	}
+
+	next := time.Now().Add(o.backoff(job.attempts))
+	o.logger.Warn("email delivery failed, will retry", "job_id", job.id, "template", job.template, "attempts", job.attempts, "retry_at", next, "error", err)
+	_, dbErr := o.db.Exec("UPDATE email_outbox SET status = 'pending', next_attempt_at = ?, locked_until = NULL, last_error = ? WHERE id = ?", next, err.Error(), job.id)
+	return true, dbErr
+}
+
+// Ping checks the backend the outbox delivers through.
+func (o *Outbox) Ping(ctx context.Context) error {
+	return Ping(ctx, o.sender)
+}
+
+// OutboxStats describes the queue and the work of the workers in this
+// process.
+type OutboxStats struct {
+	// Jobs counts the undelivered jobs by status: pending, processing and
+	// dead.
+	Jobs map[string]int
+	// Sent and Failed count delivery attempts since the process started.
+	Sent   uint64
+	Failed uint64
+}
+
+// Stats counts the jobs still in the queue. Sent jobs are left out, since
+// the table keeps them forever.
+func (o *Outbox) Stats(ctx context.Context) (*OutboxStats, error) {
+	stats := &OutboxStats{
+		Jobs:   map[string]int{"pending": 0, "processing": 0, "dead": 0},
+		Sent:   o.sent.Load(),
+		Failed: o.failed.Load(),
+	}
+
+	rows, err := o.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM email_outbox WHERE status <> 'sent' GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		err := rows.Scan(&status, &count)
+		if err != nil {
+			return nil, err
+		}
+		stats.Jobs[status] = count
+	}
+
+	return stats, rows.Err()
+}
+
+// claim locks the oldest due job for this worker. Jobs left in processing by
+// a worker that died are reclaimed once their lease has run out.
+func (o *Outbox) claim() (*outboxJob, error) {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+	SELECT id, recipient, template, data, attempts FROM email_outbox
+	WHERE (status = 'pending' AND next_attempt_at <= ?) OR (status = 'processing' AND locked_until < ?)
+	ORDER BY id
+	LIMIT 1
+	` + o.db.Dialect.ForUpdate(true)
+	var job outboxJob
+	err = tx.QueryRow(query, now, now).Scan(&job.id, &job.recipient, &job.template, &job.data, &job.attempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	job.attempts++
+	_, err = tx.Exec("UPDATE email_outbox SET status = 'processing', attempts = ?, locked_until = ? WHERE id = ?", job.attempts, now.Add(o.config.Lease), job.id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, tx.Commit()
+}
+
+// backoff doubles the delay after every failed attempt, capped at MaxBackoff,
+// with up to 20% jitter so failed jobs do not retry in lockstep.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.config.BaseBackoff
+	for i := 1; i < attempts && delay < o.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.config.MaxBackoff {
+		delay = o.config.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
@@ -0,0 +1,168 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// flakySender fails the first failures deliveries and captures the rest.
+type flakySender struct {
+	*CaptureSender
+	failures int
+}
+
+func (s *flakySender) Send(recipient string, templateFile string, data interface{}) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("connection refused")
+	}
+	return s.CaptureSender.Send(recipient, templateFile, data)
+}
+
+func newTestOutbox(t *testing.T, sender Sender, outboxConfig OutboxConfig) *Outbox {
+	t.Helper()
+
+	db, err := database.Open(config.DatabaseConfig{Driver: "sqlite", ConnectionString: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = database.Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	return NewOutbox(db, sender, slog.New(slog.NewTextHandler(io.Discard, nil)), outboxConfig)
+}
+
+type outboxRow struct {
+	status    string
+	attempts  int
+	data      string
+	lastError sql.NullString
+}
+
+func loadJob(t *testing.T, outbox *Outbox) outboxRow {
+	t.Helper()
+
+	var row outboxRow
+	err := outbox.db.QueryRow("SELECT status, attempts, data, last_error FROM email_outbox").Scan(&row.status, &row.attempts, &row.data, &row.lastError)
+	if err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	return row
+}
+
+// process runs one job as a worker would and reports whether one was due.
+func process(t *testing.T, outbox *Outbox) bool {
+	t.Helper()
+
+	processed, err := outbox.processNext()
+	if err != nil {
+		t.Fatalf("processNext: %v", err)
+	}
+	return processed
+}
+
+func TestOutboxRetries(t *testing.T) {
+	sender := &flakySender{CaptureSender: NewCaptureSender(), failures: 2}
+	outbox := newTestOutbox(t, sender, OutboxConfig{MaxAttempts: 3, BaseBackoff: time.Nanosecond, MaxBackoff: time.Nanosecond})
+
+	err := outbox.Send("jane@example.com", "user_token.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		time.Sleep(time.Millisecond)
+		if !process(t, outbox) {
+			t.Fatalf("attempt %d: no job was due", attempt)
+		}
+		job := loadJob(t, outbox)
+		if job.status != "pending" || job.attempts != attempt || job.lastError.String != "connection refused" {
+			t.Fatalf("attempt %d: got %+v, want a pending job to retry", attempt, job)
+		}
+	}
+
+	time.Sleep(time.Millisecond)
+	if !process(t, outbox) {
+		t.Fatal("the last attempt was not due")
+	}
+	job := loadJob(t, outbox)
+	if job.status != "sent" || job.attempts != 3 || job.data != "{}" || job.lastError.Valid {
+		t.Fatalf("got %+v, want a sent job with its data cleared", job)
+	}
+	if sent := sender.SentTo("jane@example.com"); len(sent) != 1 || sent[0].Data.(map[string]interface{})["Code"] != "123456" {
+		t.Fatalf("got %+v, want the email delivered once with its data", sent)
+	}
+
+	stats, err := outbox.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Sent != 1 || stats.Failed != 2 || stats.Jobs["pending"] != 0 {
+		t.Fatalf("got stats %+v", stats)
+	}
+}
+
+func TestOutboxBacksOff(t *testing.T) {
+	sender := &flakySender{CaptureSender: NewCaptureSender(), failures: 1}
+	outbox := newTestOutbox(t, sender, OutboxConfig{BaseBackoff: time.Hour})
+
+	err := outbox.Send("jane@example.com", "user_token.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !process(t, outbox) {
+		t.Fatal("the new job was not due")
+	}
+	if process(t, outbox) {
+		t.Fatal("the failed job was retried before its backoff ran out")
+	}
+}
+
+func TestOutboxDeadLetter(t *testing.T) {
+	sender := &flakySender{CaptureSender: NewCaptureSender(), failures: 10}
+	outbox := newTestOutbox(t, sender, OutboxConfig{MaxAttempts: 2, BaseBackoff: time.Nanosecond, MaxBackoff: time.Nanosecond})
+
+	err := outbox.Send("jane@example.com", "user_token.html", tokenData)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		time.Sleep(time.Millisecond)
+		if !process(t, outbox) {
+			t.Fatalf("attempt %d: no job was due", attempt)
+		}
+	}
+
+	job := loadJob(t, outbox)
+	if job.status != "dead" || job.attempts != 2 || job.data != "{}" || job.lastError.String != "connection refused" {
+		t.Fatalf("got %+v, want a dead job with its data cleared", job)
+	}
+
+	time.Sleep(time.Millisecond)
+	if process(t, outbox) {
+		t.Fatal("a dead job was retried")
+	}
+	if sent := sender.Sent(); len(sent) != 0 {
+		t.Fatalf("got %d emails delivered, want none", len(sent))
+	}
+
+	stats, err := outbox.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Jobs["dead"] != 1 || stats.Failed != 2 {
+		t.Fatalf("got stats %+v", stats)
+	}
+}
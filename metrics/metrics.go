@@ -0,0 +1,162 @@
+// Package metrics exposes the health and the impact of the API to
+// Prometheus: HTTP traffic, the database pool, the email outbox and the
+// business events reported by the services.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// scrapeTimeout bounds the queries run while collecting, so a slow database
+// does not hang the scrape.
+const scrapeTimeout = 2 * time.Second
+
+// Metrics owns a registry with every metric of the API. It implements
+// services.Recorder.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	bagsListed   prometheus.Counter
+	bagsSold     prometheus.Counter
+	bagsPickedUp prometheus.Counter
+	foodRescued  prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		bagsListed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "magic_bags_listed_total",
+			Help: "Units of magic bags put on sale in new listings.",
+		}),
+		bagsSold: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "magic_bags_sold_total",
+			Help: "Magic bags bought, counted when the purchase succeeds.",
+		}),
+		bagsPickedUp: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "magic_bags_picked_up_total",
+			Help: "Magic bags collected from partners.",
+		}),
+		foodRescued: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "food_rescued_kilograms_total",
+			Help: "Estimated kilograms of food in collected magic bags. Bags without a weight add nothing.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.bagsListed,
+		m.bagsSold,
+		m.bagsPickedUp,
+		m.foodRescued,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records one served request. route is the route pattern,
+// not the path, so that ids in URLs do not create a series each.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// RegisterDB adds the connection pool statistics of db, labelled with name.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterOutbox adds the queue depth and delivery counts of the outbox.
+func (m *Metrics) RegisterOutbox(outbox *mailer.Outbox) {
+	m.registry.MustRegister(&outboxCollector{outbox: outbox})
+}
+
+func (m *Metrics) BagsListed(count int) {
+	m.bagsListed.Add(float64(count))
+}
+
+func (m *Metrics) BagSold() {
+	m.bagsSold.Inc()
+}
+
+func (m *Metrics) BagPickedUp(weightKg float64) {
+	m.bagsPickedUp.Inc()
+	m.foodRescued.Add(weightKg)
+}
+
+var (
+	outboxJobsDesc = prometheus.NewDesc(
+		"email_outbox_jobs",
+		"Undelivered emails in the outbox by status.",
+		[]string{"status"}, nil,
+	)
+	outboxSentDesc = prometheus.NewDesc(
+		"email_outbox_sent_total",
+		"Emails delivered by this process.",
+		nil, nil,
+	)
+	outboxFailedDesc = prometheus.NewDesc(
+		"email_outbox_failures_total",
+		"Failed delivery attempts in this process, including those retried later.",
+		nil, nil,
+	)
+)
+
+// outboxCollector reads the outbox on every scrape, since the queue lives in
+// the database and is shared with other instances.
+type outboxCollector struct {
+	outbox *mailer.Outbox
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- outboxJobsDesc
+	ch <- outboxSentDesc
+	ch <- outboxFailedDesc
+}
+
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	stats, err := c.outbox.Stats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(outboxJobsDesc, err)
+		return
+	}
+
+	for status, count := range stats.Jobs {
+		ch <- prometheus.MustNewConstMetric(outboxJobsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+	ch <- prometheus.MustNewConstMetric(outboxSentDesc, prometheus.CounterValue, float64(stats.Sent))
+	ch <- prometheus.MustNewConstMetric(outboxFailedDesc, prometheus.CounterValue, float64(stats.Failed))
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditSuspendUser        AuditAction = "suspend_user"
+	AuditReactivateUser     AuditAction = "reactivate_user"
+	AuditForcePasswordReset AuditAction = "force_password_reset"
+	AuditChangeUserType     AuditAction = "change_user_type"
+	AuditDeleteUser         AuditAction = "delete_user"
+)
+
+// AuditEntry records one action an admin took on a user account.
+type AuditEntry struct {
+	Id           int64       `json:"id"`
+	ActorID      int64       `json:"actor_id"`
+	TargetUserID int64       `json:"target_user_id"`
+	Action       AuditAction `json:"action"`
+	// OldValues and NewValues hold the account fields admins manage, as they
+	// were before and after the action.
+	OldValues   json.RawMessage `json:"old_values"`
+	NewValues   json.RawMessage `json:"new_values"`
+	Reason      string          `json:"reason"`
+	DateCreated time.Time
+}
+
+// AccountValues returns the fields of the user that admins manage, as
+// recorded in the audit log.
+func AccountValues(user *User) json.RawMessage {
+	values, _ := json.Marshal(map[string]any{
+		"user_type":                  user.UserType,
+		"suspended_at":               user.SuspendedAt,
+		"deleted_at":                 user.DeletedAt,
+		"password_reset_required_at": user.PasswordResetRequiredAt,
+	})
+	return values
+}
+
+// AdminActionRequest is the body of the admin actions on a user account,
+// which must all give a reason for the audit log.
+type AdminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ChangeUserTypeRequest struct {
+	UserType UserType `json:"user_type"`
+	Reason   string   `json:"reason"`
+}
+
+func ValidateAuditReason(v *validator.Validator, reason string) {
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(len(reason) <= 500, "reason", "must not be more than 500 bytes long")
+}
+
+func ValidateChangeUserType(v *validator.Validator, req *ChangeUserTypeRequest) {
+	ValidateUserType(v, req.UserType)
+	ValidateAuditReason(v, req.Reason)
+}
@@ -0,0 +1,117 @@
+package models
+
+// ErrorCode identifies an error for API clients. Codes are stable, so
+// clients switch on them rather than on messages, which may be reworded.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest      ErrorCode = "INVALID_REQUEST"
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeRouteNotFound       ErrorCode = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed    ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeInternal            ErrorCode = "INTERNAL_ERROR"
+	CodeUnauthenticated     ErrorCode = "AUTHENTICATION_REQUIRED"
+	CodeInvalidToken        ErrorCode = "INVALID_ACCESS_TOKEN"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
+	CodeInvalidSignature    ErrorCode = "INVALID_WEBHOOK_SIGNATURE"
+	CodeEmailTaken          ErrorCode = "EMAIL_TAKEN"
+	CodePhoneTaken          ErrorCode = "PHONE_NUMBER_TAKEN"
+	CodeInvalidLogin        ErrorCode = "INVALID_CREDENTIALS"
+	CodeAccountInactive     ErrorCode = "ACCOUNT_INACTIVE"
+	CodeAccountSuspended    ErrorCode = "ACCOUNT_SUSPENDED"
+	CodePasswordReset       ErrorCode = "PASSWORD_RESET_REQUIRED"
+	CodeUserNotFound        ErrorCode = "USER_NOT_FOUND"
+	CodeUserDeleted         ErrorCode = "USER_DELETED"
+	CodeUserSuspended       ErrorCode = "USER_ALREADY_SUSPENDED"
+	CodeUserNotSuspended    ErrorCode = "USER_NOT_SUSPENDED"
+	CodeUserTypeUnchanged   ErrorCode = "USER_TYPE_UNCHANGED"
+	CodeOwnAccount          ErrorCode = "OWN_ACCOUNT"
+	CodeTokenNotFound       ErrorCode = "TOKEN_NOT_FOUND"
+	CodeTokenExpired        ErrorCode = "TOKEN_EXPIRED"
+	CodeInvalidRefresh      ErrorCode = "INVALID_REFRESH_TOKEN"
+	CodeInvalidCode         ErrorCode = "INVALID_VERIFICATION_CODE"
+	CodeVerificationLocked  ErrorCode = "VERIFICATION_LOCKED"
+	CodePartnerNotFound     ErrorCode = "PARTNER_NOT_FOUND"
+	CodePartnerExists       ErrorCode = "PARTNER_EXISTS"
+	CodePartnerReviewed     ErrorCode = "PARTNER_ALREADY_REVIEWED"
+	CodePartnerMissing      ErrorCode = "PARTNER_PROFILE_MISSING"
+	CodePartnerUnapproved   ErrorCode = "PARTNER_NOT_APPROVED"
+	CodeMagicBagNotFound    ErrorCode = "MAGIC_BAG_NOT_FOUND"
+	CodeMagicBagWithdrawn   ErrorCode = "MAGIC_BAG_WITHDRAWN"
+	CodeMagicBagSoldOut     ErrorCode = "MAGIC_BAG_SOLD_OUT"
+	CodePickupEnded         ErrorCode = "PICKUP_ENDED"
+	CodeProductNotFound     ErrorCode = "PRODUCT_NOT_FOUND"
+	CodeInvalidBagItems     ErrorCode = "INVALID_BAG_ITEMS"
+	CodeProductInUse        ErrorCode = "PRODUCT_IN_USE"
+	CodeTransactionNotFound ErrorCode = "TRANSACTION_NOT_FOUND"
+	CodeDailyLimit          ErrorCode = "DAILY_PURCHASE_LIMIT"
+	CodePaymentDeclined     ErrorCode = "PAYMENT_DECLINED"
+	CodePaymentState        ErrorCode = "INVALID_PAYMENT_STATE"
+	CodeAlreadyPickedUp     ErrorCode = "ALREADY_PICKED_UP"
+	CodeNotPickedUp         ErrorCode = "NOT_PICKED_UP"
+	CodeFeedbackNotFound    ErrorCode = "FEEDBACK_NOT_FOUND"
+	CodeFeedbackExists      ErrorCode = "FEEDBACK_EXISTS"
+)
+
+// Error is an error the API reports to its clients. Message is written for
+// them; Err is the underlying cause, which is logged but never sent.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	// Detail explains this occurrence, such as why a body did not parse.
+	Detail string
+	// Fields holds a message per invalid field when validation failed.
+	Fields map[string]string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any Error with the same code, so that errors.Is(err,
+// ErrTokenExpired) holds for a copy carrying a detail. Every sentinel error
+// therefore needs a code of its own.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail returns a copy of e explaining this occurrence.
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+var (
+	ErrInternal         = &Error{Code: CodeInternal, Message: "Something went wrong. Try again"}
+	ErrRouteNotFound    = &Error{Code: CodeRouteNotFound, Message: "Route not found"}
+	ErrMethodNotAllowed = &Error{Code: CodeMethodNotAllowed, Message: "Method not allowed"}
+	ErrUnauthenticated  = &Error{Code: CodeUnauthenticated, Message: "Authentication required"}
+	ErrInvalidToken     = &Error{Code: CodeInvalidToken, Message: "Invalid or expired access token"}
+	ErrForbidden        = &Error{Code: CodeForbidden, Message: "You are not allowed to perform this action"}
+	ErrInvalidSignature = &Error{Code: CodeInvalidSignature, Message: "Invalid webhook signature"}
+)
+
+// InvalidRequest reports a request that could not be read, such as a body
+// that is not valid JSON. The cause is passed on to the client as detail.
+func InvalidRequest(message string, cause error) *Error {
+	e := &Error{Code: CodeInvalidRequest, Message: message}
+	if cause != nil {
+		e.Detail = cause.Error()
+	}
+	return e
+}
+
+// ValidationFailed reports the failed checks of a validator.
+func ValidationFailed(fields map[string]string) *Error {
+	return &Error{Code: CodeValidationFailed, Message: "Some fields are invalid", Fields: fields}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	sentinels := []*Error{ErrUserSuspended, ErrUserNotSuspended, ErrUserTypeUnchanged, ErrUserDeleted, ErrOwnAccount}
+
+	for _, err := range sentinels {
+		wrapped := fmt.Errorf("suspend user: %w", err)
+		for _, target := range sentinels {
+			if got, want := errors.Is(wrapped, target), err == target; got != want {
+				t.Errorf("errors.Is(%q, %q) = %v, want %v", err.Message, target.Message, got, want)
+			}
+		}
+	}
+
+	detailed := ErrTokenExpired.WithDetail("expired an hour ago")
+	if !errors.Is(detailed, ErrTokenExpired) {
+		t.Error("a copy with a detail does not match its sentinel")
+	}
+}
@@ -1,6 +1,15 @@
 package models
 
-import "time"
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"time"
+)
+
+var (
+	ErrFeedbackNotFound = &Error{Code: CodeFeedbackNotFound, Message: "Feedback not found"}
+	ErrFeedbackExists   = &Error{Code: CodeFeedbackExists, Message: "Feedback has already been given for this transaction"}
+	ErrNotPickedUp      = &Error{Code: CodeNotPickedUp, Message: "Feedback can only be given after the magic bag is picked up"}
+)
 
 type Feedback struct {
 	Id            int64  `json:"id"`
@@ -9,8 +18,42 @@ type Feedback struct {
 	DateCreated   time.Time
 	DateUpdated   time.Time
 	TransactionID int64 `json:"transaction_id"`
+	UserID        int64 `json:"user_id"`
+	PartnerID     int64 `json:"partner_id"`
+}
+
+// PublicFeedback is feedback as anyone browsing a partner sees it. It leaves
+// out who gave it and for which transaction.
+type PublicFeedback struct {
+	Id          int64  `json:"id"`
+	Comment     string `json:"comment"`
+	Rating      int    `json:"rating"`
+	DateCreated time.Time
+	DateUpdated time.Time
+	PartnerID   int64 `json:"partner_id"`
 }
 
-func save() {
+func (f *Feedback) Public() *PublicFeedback {
+	return &PublicFeedback{
+		Id:          f.Id,
+		Comment:     f.Comment,
+		Rating:      f.Rating,
+		DateCreated: f.DateCreated,
+		DateUpdated: f.DateUpdated,
+		PartnerID:   f.PartnerID,
+	}
+}
+
+// PartnerRating is the aggregate of every rating a partner has received.
+// Distribution maps each star value to the number of ratings with it.
+type PartnerRating struct {
+	PartnerID    int64       `json:"partner_id"`
+	Average      float64     `json:"average"`
+	Count        int         `json:"count"`
+	Distribution map[int]int `json:"distribution"`
+}
 
+func ValidateFeedback(v *validator.Validator, f *Feedback) {
+	v.Check(f.Rating >= 1 && f.Rating <= 5, "rating", "must be between 1 and 5")
+	v.Check(len(f.Comment) <= 2000, "comment", "must not be more than 2000 bytes long")
 }
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"time"
+)
+
+// The specs below are the fields the list endpoints let clients filter and
+// sort on. The SQL stores map the same names to columns.
+
+var MagicBagListing = listing.Spec[*MagicBag]{
+	Fields: map[string]listing.Field[*MagicBag]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(b *MagicBag) any { return b.ID }},
+		"partner_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(b *MagicBag) any { return b.PartnerID }},
+		"status": {Kind: listing.String, Operators: listing.Equality, Values: []string{string(MagicBagActive), string(MagicBagWithdrawn)},
+			Value: func(b *MagicBag) any { return string(b.Status) }},
+		"bag_price": {Kind: listing.Float, Operators: listing.Ordered, Sortable: true,
+			Value: func(b *MagicBag) any { return b.BagPrice }},
+		"quantity": {Kind: listing.Int, Operators: listing.Ordered, Sortable: true,
+			Value: func(b *MagicBag) any { return int64(b.Quantity) }},
+		"weight_kg": {Kind: listing.Float, Operators: listing.Ordered, Sortable: true,
+			Value: func(b *MagicBag) any { return b.WeightKg }},
+		"pickup_start": {Kind: listing.Time, Operators: listing.Ordered,
+			Value: func(b *MagicBag) any { return optionalTime(b.PickupStart) }},
+		"pickup_end": {Kind: listing.Time, Operators: listing.Ordered,
+			Value: func(b *MagicBag) any { return optionalTime(b.PickupEnd) }},
+		// A bag without products matches no allergen or dietary filter,
+		// since nothing is known about what is in it.
+		"allergens": {Kind: listing.String, Operators: []listing.Operator{listing.None}, Values: names(Allergens),
+			Value: func(b *MagicBag) any { return labels(b.Allergens) }},
+		"dietary_tags": {Kind: listing.String, Operators: []listing.Operator{listing.All}, Values: names(DietaryTags),
+			Value: func(b *MagicBag) any { return labels(b.DietaryTags) }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(b *MagicBag) any { return b.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+var ProductListing = listing.Spec[*Product]{
+	Fields: map[string]listing.Field[*Product]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(p *Product) any { return p.Id }},
+		"name": {Kind: listing.String, Operators: listing.Text, Sortable: true,
+			Value: func(p *Product) any { return p.Name }},
+		"category": {Kind: listing.String, Operators: listing.Equality, Values: names(ProductCategories),
+			Value: func(p *Product) any { return string(p.Category) }},
+		"allergens": {Kind: listing.String, Operators: []listing.Operator{listing.None}, Values: names(Allergens),
+			Value: func(p *Product) any { return names(p.Allergens) }},
+		"dietary_tags": {Kind: listing.String, Operators: []listing.Operator{listing.All}, Values: names(DietaryTags),
+			Value: func(p *Product) any { return names(p.DietaryTags) }},
+		"unit_weight_kg": {Kind: listing.Float, Operators: listing.Ordered, Sortable: true,
+			Value: func(p *Product) any { return p.UnitWeightKg }},
+		"partner_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(p *Product) any { return optionalInt(p.PartnerID) }},
+		"shared": {Kind: listing.Bool, Operators: []listing.Operator{listing.Eq},
+			Value: func(p *Product) any { return p.PartnerID == nil }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(p *Product) any { return p.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "name"}},
+}
+
+var PartnerListing = listing.Spec[*Partner]{
+	Fields: map[string]listing.Field[*Partner]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(p *Partner) any { return p.ID }},
+		"status": {Kind: listing.String, Operators: listing.Equality, Values: []string{string(PartnerPending), string(PartnerApproved), string(PartnerRejected)},
+			Value: func(p *Partner) any { return string(p.Status) }},
+		"reviewed_at": {Kind: listing.Time, Operators: listing.Ordered,
+			Value: func(p *Partner) any { return optionalTime(p.ReviewedAt) }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(p *Partner) any { return p.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+var TransactionListing = listing.Spec[*Transaction]{
+	Fields: map[string]listing.Field[*Transaction]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(t *Transaction) any { return t.Id }},
+		"partner_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(t *Transaction) any { return t.PartnerID }},
+		"magic_bag_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(t *Transaction) any { return t.MagicBagID }},
+		"amount": {Kind: listing.Float, Operators: listing.Ordered, Sortable: true,
+			Value: func(t *Transaction) any { return t.Amount }},
+		"payment_type": {Kind: listing.String, Operators: listing.Equality, Values: []string{string(CASH), string(CARD)},
+			Value: func(t *Transaction) any { return string(t.PaymentType) }},
+		"payment_status": {Kind: listing.String, Operators: listing.Equality,
+			Values: []string{string(payment.StatusPending), string(payment.StatusAuthorized), string(payment.StatusCaptured), string(payment.StatusRefunded), string(payment.StatusFailed)},
+			Value:  func(t *Transaction) any { return string(t.PaymentStatus) }},
+		// purchase_day is a YYYY-MM-DD string, which orders like the day.
+		"purchase_day": {Kind: listing.String, Operators: listing.Ordered, Sortable: true,
+			Value: func(t *Transaction) any { return t.PurchaseDay }},
+		"picked_up_at": {Kind: listing.Time, Operators: listing.Ordered,
+			Value: func(t *Transaction) any { return optionalTime(t.PickedUpAt) }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(t *Transaction) any { return t.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+var FeedbackListing = listing.Spec[*Feedback]{
+	Fields: map[string]listing.Field[*Feedback]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(f *Feedback) any { return f.Id }},
+		"rating": {Kind: listing.Int, Operators: listing.Ordered, Sortable: true,
+			Value: func(f *Feedback) any { return int64(f.Rating) }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(f *Feedback) any { return f.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+var UserListing = listing.Spec[*User]{
+	Fields: map[string]listing.Field[*User]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(u *User) any { return u.Id }},
+		"fullname": {Kind: listing.String, Operators: listing.Text, Sortable: true,
+			Value: func(u *User) any { return u.FullName }},
+		"email": {Kind: listing.String, Operators: listing.Text, Sortable: true,
+			Value: func(u *User) any { return u.Email }},
+		"user_type": {Kind: listing.String, Operators: listing.Equality, Values: []string{string(ADMIN), string(PARTNERS), string(WASTEWARRIOR)},
+			Value: func(u *User) any { return string(u.UserType) }},
+		"status": {Kind: listing.String, Operators: listing.Equality, Values: []string{UserActive, UserInactive},
+			Value: func(u *User) any { return u.Status }},
+		"suspended": {Kind: listing.Bool, Operators: []listing.Operator{listing.Eq},
+			Value: func(u *User) any { return u.SuspendedAt != nil }},
+		"deleted": {Kind: listing.Bool, Operators: []listing.Operator{listing.Eq},
+			Value: func(u *User) any { return u.DeletedAt != nil }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(u *User) any { return u.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+var AuditListing = listing.Spec[*AuditEntry]{
+	Fields: map[string]listing.Field[*AuditEntry]{
+		"id": {Kind: listing.Int, Operators: listing.Equality, Sortable: true,
+			Value: func(e *AuditEntry) any { return e.Id }},
+		"actor_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(e *AuditEntry) any { return e.ActorID }},
+		"target_user_id": {Kind: listing.Int, Operators: listing.Equality,
+			Value: func(e *AuditEntry) any { return e.TargetUserID }},
+		"action": {Kind: listing.String, Operators: listing.Equality,
+			Values: []string{string(AuditSuspendUser), string(AuditReactivateUser), string(AuditForcePasswordReset), string(AuditChangeUserType), string(AuditDeleteUser)},
+			Value:  func(e *AuditEntry) any { return string(e.Action) }},
+		"date_created": {Kind: listing.Time, Operators: listing.Ordered, Sortable: true,
+			Value: func(e *AuditEntry) any { return e.DateCreated }},
+	},
+	Sort: []listing.Sort{{Field: "date_created", Desc: true}},
+}
+
+// labels returns the labels of a bag as a set, or nil when the bag is not
+// labelled.
+func labels[T ~string](values []T) any {
+	if values == nil {
+		return nil
+	}
+	return names(values)
+}
+
+func optionalInt(n *int64) any {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// optionalTime returns nil rather than a nil *time.Time, which would not
+// compare equal to nil once stored in an interface.
+func optionalTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
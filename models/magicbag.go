@@ -1,13 +1,91 @@
 package models
 
-import "time"
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type MagicBagStatus string
+
+const (
+	MagicBagActive    MagicBagStatus = "active"
+	MagicBagWithdrawn MagicBagStatus = "withdrawn"
+)
+
+var (
+	ErrMagicBagNotFound  = &Error{Code: CodeMagicBagNotFound, Message: "Magic bag not found"}
+	ErrMagicBagWithdrawn = &Error{Code: CodeMagicBagWithdrawn, Message: "Magic bag has been withdrawn"}
+	ErrInvalidBagItems   = &Error{Code: CodeInvalidBagItems, Message: "One or more products do not exist"}
+	ErrPickupEnded       = &Error{Code: CodePickupEnded, Message: "The pickup window of this magic bag has ended"}
+)
 
 type MagicBag struct {
-	ID          int64   `json:"id"`
-	BagPrice    float64 `json:"bag_price"`
+	ID          int64          `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	BagPrice    float64        `json:"bag_price"`
+	Quantity    int            `json:"quantity"`
+	WeightKg    float64        `json:"weight_kg"`
+	PickupStart *time.Time     `json:"pickup_start"`
+	PickupEnd   *time.Time     `json:"pickup_end"`
+	Status      MagicBagStatus `json:"status"`
 	DateCreated time.Time
 	DateUpdated time.Time
-	PartnerID   int64 `json:"partner_id"`
+	PartnerID   int64          `json:"partner_id"`
+	Items       []MagicBagItem `json:"items,omitempty"`
+	// Allergens and DietaryTags describe the contents without revealing
+	// them. Both are nil when the bag lists no products.
+	Allergens   []Allergen   `json:"allergens"`
+	DietaryTags []DietaryTag `json:"dietary_tags"`
+}
+
+// PickupEnded reports whether the bag can no longer be collected at now.
+func (b *MagicBag) PickupEnded(now time.Time) bool {
+	return b.PickupEnd != nil && !b.PickupEnd.After(now)
+}
+
+// MagicBagChanges is the body of a bag update. Fields left out of the request
+// are nil and keep their stored value. Quantity in particular is only written
+// when sent, so editing the title cannot undo a sale made in the meantime.
+type MagicBagChanges struct {
+	Title       *string         `json:"title"`
+	Description *string         `json:"description"`
+	BagPrice    *float64        `json:"bag_price"`
+	Quantity    *int            `json:"quantity"`
+	WeightKg    *float64        `json:"weight_kg"`
+	PickupStart *time.Time      `json:"pickup_start"`
+	PickupEnd   *time.Time      `json:"pickup_end"`
+	Items       *[]MagicBagItem `json:"items"`
+}
+
+// Apply sets the changed fields on bag.
+func (c *MagicBagChanges) Apply(bag *MagicBag) {
+	if c.Title != nil {
+		bag.Title = *c.Title
+	}
+	if c.Description != nil {
+		bag.Description = *c.Description
+	}
+	if c.BagPrice != nil {
+		bag.BagPrice = *c.BagPrice
+	}
+	if c.Quantity != nil {
+		bag.Quantity = *c.Quantity
+	}
+	if c.WeightKg != nil {
+		bag.WeightKg = *c.WeightKg
+	}
+	if c.PickupStart != nil {
+		bag.PickupStart = c.PickupStart
+	}
+	if c.PickupEnd != nil {
+		bag.PickupEnd = c.PickupEnd
+	}
+	if c.Items != nil {
+		bag.Items = *c.Items
+	}
 }
 
 type MagicBagItem struct {
@@ -17,6 +95,53 @@ type MagicBagItem struct {
 	ProductID  int64 `json:"product_id"`
 }
 
-func SaveMagicBag() {
+// Label sets the allergens and dietary tags of the bag from the products in
+// it: every allergen any of them has, and the tags all of them share. A bag
+// without products is left unlabelled, since nothing is known about it.
+func (b *MagicBag) Label(products []*Product) {
+	b.Allergens, b.DietaryTags = nil, nil
+	if len(products) == 0 {
+		return
+	}
+
+	b.Allergens = []Allergen{}
+	for _, allergen := range Allergens {
+		for _, product := range products {
+			if slices.Contains(product.Allergens, allergen) {
+				b.Allergens = append(b.Allergens, allergen)
+				break
+			}
+		}
+	}
+
+	b.DietaryTags = []DietaryTag{}
+	for _, tag := range DietaryTags {
+		shared := true
+		for _, product := range products {
+			shared = shared && slices.Contains(product.DietaryTags, tag)
+		}
+		if shared {
+			b.DietaryTags = append(b.DietaryTags, tag)
+		}
+	}
+}
+
+func ValidateMagicBag(v *validator.Validator, bag *MagicBag) {
+	v.Check(bag.Title != "", "title", "must be provided")
+	v.Check(len(bag.Title) <= 100, "title", "must not be more than 100 bytes long")
+	v.Check(bag.BagPrice > 0, "bag_price", "must be greater than zero")
+	v.Check(bag.Quantity >= 0, "quantity", "must not be negative")
+	v.Check(bag.WeightKg >= 0, "weight_kg", "must not be negative")
+	v.Check(bag.WeightKg < 10000, "weight_kg", "must be less than 10000")
+	if bag.PickupStart != nil && bag.PickupEnd != nil {
+		v.Check(bag.PickupEnd.After(*bag.PickupStart), "pickup_end", "must be after pickup_start")
+	}
 
+	productIds := make([]string, 0, len(bag.Items))
+	for _, item := range bag.Items {
+		v.Check(item.ProductID > 0, "items", "every item must have a product_id")
+		v.Check(item.Quantity > 0, "items", "every item quantity must be greater than zero")
+		productIds = append(productIds, strconv.FormatInt(item.ProductID, 10))
+	}
+	v.Check(validator.Unique(productIds), "items", "must not contain the same product twice")
 }
@@ -1,17 +1,68 @@
 package models
 
-import "time"
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"time"
+)
+
+type PartnerStatus string
+
+const (
+	PartnerPending  PartnerStatus = "pending"
+	PartnerApproved PartnerStatus = "approved"
+	PartnerRejected PartnerStatus = "rejected"
+)
+
+var (
+	ErrPartnerNotFound      = &Error{Code: CodePartnerNotFound, Message: "Partner not found"}
+	ErrPartnerExists        = &Error{Code: CodePartnerExists, Message: "Partner profile already exists"}
+	ErrPartnerAlreadyReview = &Error{Code: CodePartnerReviewed, Message: "Partner profile is not pending review"}
+	ErrPartnerMissing       = &Error{Code: CodePartnerMissing, Message: "Partner profile has not been created"}
+	ErrPartnerUnapproved    = &Error{Code: CodePartnerUnapproved, Message: "Partner profile has not been approved"}
+)
 
 type Partner struct {
-	ID          int64  `json:"id"`
-	BRNumber    int    `json:"business_number"`
-	Logo        string `json:"logo"`
-	Address     string `json:"address"`
-	DateCreated time.Time
-	DateUpdated time.Time
-	UserID      int64 `json:"user_id"`
+	ID              int64         `json:"id"`
+	BRNumber        string        `json:"business_number"`
+	Logo            string        `json:"logo"`
+	Address         string        `json:"address"`
+	Timezone        string        `json:"timezone"`
+	Status          PartnerStatus `json:"status"`
+	RejectionReason string        `json:"rejection_reason,omitempty"`
+	ReviewedAt      *time.Time    `json:"reviewed_at,omitempty"`
+	DateCreated     time.Time
+	DateUpdated     time.Time
+	UserID          int64 `json:"user_id"`
 }
 
-func (p *Partner) SavePartner() {
+// PublicPartner is the profile waste warriors see. It leaves out the review
+// details and carries the partner's rating aggregate.
+type PublicPartner struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Logo    string         `json:"logo"`
+	Address string         `json:"address"`
+	Rating  *PartnerRating `json:"rating"`
+}
+
+func ValidatePartner(v *validator.Validator, p *Partner) {
+	v.Check(p.BRNumber != "", "business_number", "must be provided")
+	v.Check(len(p.BRNumber) <= 30, "business_number", "must not be more than 30 bytes long")
+	v.Check(p.Address != "", "address", "must be provided")
+	v.Check(len(p.Address) <= 255, "address", "must not be more than 255 bytes long")
+	v.Check(len(p.Logo) <= 255, "logo", "must not be more than 255 bytes long")
+
+	// LoadLocation reads "" as UTC, so an empty zone is checked for first.
+	_, err := time.LoadLocation(p.Timezone)
+	v.Check(p.Timezone != "" && err == nil, "timezone", "must be a valid IANA time zone such as Europe/London")
+}
 
+// Location returns the partner's time zone, which defines what counts as
+// "one day" for the purchase limit.
+func (p *Partner) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"testing"
+)
+
+func TestValidatePartner(t *testing.T) {
+	partner := &Partner{BRNumber: "BR-1", Address: "1 Market Street"}
+
+	v := validator.New()
+	ValidatePartner(v, partner)
+	if _, ok := v.Errors["timezone"]; !ok {
+		t.Fatalf("got errors %v, want one for the missing time zone", v.Errors)
+	}
+	if partner.Timezone != "" {
+		t.Fatalf("validating set the time zone to %q", partner.Timezone)
+	}
+
+	for _, timezone := range []string{"UTC", "Europe/London"} {
+		partner.Timezone = timezone
+		v := validator.New()
+		if ValidatePartner(v, partner); !v.Valid() {
+			t.Errorf("%s: got errors %v", timezone, v.Errors)
+		}
+	}
+}
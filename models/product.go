@@ -1,18 +1,143 @@
 package models
 
-import "time"
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"slices"
+	"time"
+)
 
+var (
+	ErrProductNotFound = &Error{Code: CodeProductNotFound, Message: "Product not found"}
+	ErrProductInUse    = &Error{Code: CodeProductInUse, Message: "Product is listed in a magic bag"}
+)
+
+type ProductCategory string
+
+const (
+	CategoryBakery        ProductCategory = "bakery"
+	CategoryProduce       ProductCategory = "produce"
+	CategoryDairy         ProductCategory = "dairy"
+	CategoryMeat          ProductCategory = "meat"
+	CategoryFish          ProductCategory = "fish"
+	CategoryPreparedMeals ProductCategory = "prepared_meals"
+	CategoryGroceries     ProductCategory = "groceries"
+	CategoryDrinks        ProductCategory = "drinks"
+	CategoryOther         ProductCategory = "other"
+)
+
+var ProductCategories = []ProductCategory{
+	CategoryBakery, CategoryProduce, CategoryDairy, CategoryMeat, CategoryFish,
+	CategoryPreparedMeals, CategoryGroceries, CategoryDrinks, CategoryOther,
+}
+
+// Allergen is one of the 14 allergens EU food law requires sellers to
+// declare.
+type Allergen string
+
+const (
+	AllergenCelery      Allergen = "celery"
+	AllergenGluten      Allergen = "gluten"
+	AllergenCrustaceans Allergen = "crustaceans"
+	AllergenEggs        Allergen = "eggs"
+	AllergenFish        Allergen = "fish"
+	AllergenLupin       Allergen = "lupin"
+	AllergenMilk        Allergen = "milk"
+	AllergenMolluscs    Allergen = "molluscs"
+	AllergenMustard     Allergen = "mustard"
+	AllergenNuts        Allergen = "nuts"
+	AllergenPeanuts     Allergen = "peanuts"
+	AllergenSesame      Allergen = "sesame"
+	AllergenSoya        Allergen = "soya"
+	AllergenSulphites   Allergen = "sulphites"
+)
+
+// Allergens lists every allergen in the order they are reported in.
+var Allergens = []Allergen{
+	AllergenCelery, AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish,
+	AllergenLupin, AllergenMilk, AllergenMolluscs, AllergenMustard, AllergenNuts,
+	AllergenPeanuts, AllergenSesame, AllergenSoya, AllergenSulphites,
+}
+
+type DietaryTag string
+
+const (
+	DietVegan      DietaryTag = "vegan"
+	DietHalal      DietaryTag = "halal"
+	DietGlutenFree DietaryTag = "gluten_free"
+)
+
+// DietaryTags lists every tag in the order they are reported in.
+var DietaryTags = []DietaryTag{DietVegan, DietHalal, DietGlutenFree}
+
+// Product is an item partners put in magic bags. Products without a partner
+// make up the shared catalog, which admins look after and every partner can
+// use; the others belong to the partner that added them.
 type Product struct {
-	Id          int64  `json:"id"`
-	Name        string `json:"name"`
-	DateCreated time.Time
-	DateUpdated time.Time
+	Id          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Category    ProductCategory `json:"category"`
+	Allergens   []Allergen      `json:"allergens"`
+	DietaryTags []DietaryTag    `json:"dietary_tags"`
+	// UnitWeightKg is the estimated weight of one unit.
+	UnitWeightKg float64 `json:"unit_weight_kg"`
+	PartnerID    *int64  `json:"partner_id"`
+	DateCreated  time.Time
+	DateUpdated  time.Time
 }
 
-func (p *Product) SaveProduct() {
+// veganExcludes are the allergens only found in animal products.
+var veganExcludes = []Allergen{AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenMilk, AllergenMolluscs}
 
+func ValidateProduct(v *validator.Validator, product *Product) {
+	v.Check(product.Name != "", "name", "must be provided")
+	v.Check(len(product.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(validator.In(string(product.Category), names(ProductCategories)...), "category", "must be a known category")
+	v.Check(product.UnitWeightKg >= 0, "unit_weight_kg", "must not be negative")
+	v.Check(product.UnitWeightKg < 1000, "unit_weight_kg", "must be less than 1000")
+
+	allergens := names(product.Allergens)
+	for _, allergen := range allergens {
+		v.Check(validator.In(allergen, names(Allergens)...), "allergens", "must only list known allergens")
+	}
+	v.Check(validator.Unique(allergens), "allergens", "must not list an allergen twice")
+
+	tags := names(product.DietaryTags)
+	for _, tag := range tags {
+		v.Check(validator.In(tag, names(DietaryTags)...), "dietary_tags", "must only list known tags")
+	}
+	v.Check(validator.Unique(tags), "dietary_tags", "must not list a tag twice")
+
+	if validator.In(string(DietGlutenFree), tags...) {
+		v.Check(!validator.In(string(AllergenGluten), allergens...), "dietary_tags", "gluten_free cannot be combined with the gluten allergen")
+	}
+	if validator.In(string(DietVegan), tags...) {
+		for _, allergen := range veganExcludes {
+			v.Check(!validator.In(string(allergen), allergens...), "dietary_tags", "vegan cannot be combined with the "+string(allergen)+" allergen")
+		}
+	}
 }
 
-func (p *Product) getProductByID(id int64) {
+// SortLabels puts the allergens and tags of the product in the order they
+// are reported in.
+func (p *Product) SortLabels() {
+	allergens, tags := []Allergen{}, []DietaryTag{}
+	for _, allergen := range Allergens {
+		if slices.Contains(p.Allergens, allergen) {
+			allergens = append(allergens, allergen)
+		}
+	}
+	for _, tag := range DietaryTags {
+		if slices.Contains(p.DietaryTags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	p.Allergens, p.DietaryTags = allergens, tags
+}
 
+func names[T ~string](values []T) []string {
+	s := make([]string, len(values))
+	for i, value := range values {
+		s[i] = string(value)
+	}
+	return s
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+var ErrInvalidRefreshToken = &Error{Code: CodeInvalidRefresh, Message: "Invalid or expired refresh token"}
+
+type RefreshToken struct {
+	Id          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	TokenHash   string `json:"-"`
+	ExpireAt    time.Time
+	RevokedAt   *time.Time
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// TokenPair is returned to the client after a successful login or refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
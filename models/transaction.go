@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"time"
+)
 
 type PaymentType string
 
@@ -9,12 +13,57 @@ const (
 	CARD PaymentType = "card"
 )
 
+var (
+	ErrTransactionNotFound = &Error{Code: CodeTransactionNotFound, Message: "Transaction not found"}
+	ErrMagicBagSoldOut     = &Error{Code: CodeMagicBagSoldOut, Message: "Magic bag is sold out"}
+	ErrDailyPurchaseLimit  = &Error{Code: CodeDailyLimit, Message: "You can only buy one magic bag per partner per day"}
+	ErrPaymentDeclined     = &Error{Code: CodePaymentDeclined, Message: "Payment was declined"}
+	ErrPaymentState        = &Error{Code: CodePaymentState, Message: "Payment status does not allow this operation"}
+	ErrAlreadyPickedUp     = &Error{Code: CodeAlreadyPickedUp, Message: "Magic bag has already been picked up"}
+)
+
+// paymentTransitions lists the statuses each payment status may move to.
+var paymentTransitions = map[payment.Status][]payment.Status{
+	payment.StatusPending:    {payment.StatusAuthorized, payment.StatusCaptured, payment.StatusRefunded, payment.StatusFailed},
+	payment.StatusAuthorized: {payment.StatusCaptured, payment.StatusRefunded, payment.StatusFailed},
+	payment.StatusCaptured:   {payment.StatusRefunded},
+}
+
+// CanTransition reports whether a payment may move from one status to another.
+func CanTransition(from, to payment.Status) bool {
+	for _, status := range paymentTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Transaction struct {
-	Id          int64       `json:"id"`
-	Amount      float64     `json:"amount"`
-	PaymentType PaymentType `json:"payment_type"`
-	DateCreated time.Time
-	DateUpdated time.Time
-	UserID      int64 `json:"user_id"`
-	MagicBagID  int64 `json:"magic_bag_id"`
+	Id               int64          `json:"id"`
+	Amount           float64        `json:"amount"`
+	PaymentType      PaymentType    `json:"payment_type"`
+	PaymentStatus    payment.Status `json:"payment_status"`
+	PaymentReference string         `json:"payment_reference,omitempty"`
+	PurchaseDay      string         `json:"purchase_day"`
+	PickedUpAt       *time.Time     `json:"picked_up_at"`
+	DateCreated      time.Time
+	DateUpdated      time.Time
+	UserID           int64     `json:"user_id"`
+	PartnerID        int64     `json:"partner_id"`
+	MagicBagID       int64     `json:"magic_bag_id"`
+	MagicBag         *MagicBag `json:"magic_bag,omitempty"`
+}
+
+// ReleasesBag reports whether moving to the status puts the bag back on sale,
+// which is the case when a purchase fails or is refunded before pickup.
+func (t *Transaction) ReleasesBag(status payment.Status) bool {
+	return (status == payment.StatusFailed || status == payment.StatusRefunded) && t.PickedUpAt == nil
+}
+
+func ValidatePurchase(v *validator.Validator, paymentType PaymentType, paymentSource string) {
+	v.Check(paymentType == CASH || paymentType == CARD, "payment_type", "must be either cash or card")
+	if paymentType == CARD {
+		v.Check(paymentSource != "", "payment_source", "must be provided for card payments")
+	}
 }
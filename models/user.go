@@ -1,339 +1,231 @@
 package models
 
 import (
-	"database/sql"
-	"errors"
 	"fmt"
-	"github.com/horlathunbhosun/reducing-food-waste/database"
-	"github.com/horlathunbhosun/reducing-food-waste/mailer"
-	"github.com/horlathunbhosun/reducing-food-waste/pkg/utility"
 	"github.com/horlathunbhosun/reducing-food-waste/validator"
-	"math/rand"
-	"os"
-	"strconv"
-	"strings"
-	"sync"
 	"time"
 )
 
-var ErrDuplicateEmail = errors.New("duplicate email")
+var (
+	ErrDuplicateEmail     = &Error{Code: CodeEmailTaken, Message: "A user with this email address already exists"}
+	ErrDuplicatePhone     = &Error{Code: CodePhoneTaken, Message: "A user with this phone number already exists"}
+	ErrInvalidCredentials = &Error{Code: CodeInvalidLogin, Message: "Invalid email or password"}
+	ErrInactiveAccount    = &Error{Code: CodeAccountInactive, Message: "Account is not active"}
+	ErrAccountSuspended   = &Error{Code: CodeAccountSuspended, Message: "Account is suspended"}
+	ErrPasswordReset      = &Error{Code: CodePasswordReset, Message: "Password must be reset before signing in"}
+	ErrUserNotFound       = &Error{Code: CodeUserNotFound, Message: "User not found"}
+	ErrUserDeleted        = &Error{Code: CodeUserDeleted, Message: "User has been deleted"}
+	ErrUserSuspended      = &Error{Code: CodeUserSuspended, Message: "User is already suspended"}
+	ErrUserNotSuspended   = &Error{Code: CodeUserNotSuspended, Message: "User is not suspended"}
+	ErrUserTypeUnchanged  = &Error{Code: CodeUserTypeUnchanged, Message: "User already has this type"}
+	ErrOwnAccount         = &Error{Code: CodeOwnAccount, Message: "Admins cannot manage their own account"}
+	ErrTokenNotFound      = &Error{Code: CodeTokenNotFound, Message: "Token not found"}
+	ErrTokenExpired       = &Error{Code: CodeTokenExpired, Message: "Token has expired"}
+	ErrInvalidCode        = &Error{Code: CodeInvalidCode, Message: "Invalid email or code"}
+	ErrVerificationLocked = &Error{Code: CodeVerificationLocked, Message: "Too many wrong codes. Try again later"}
+)
 
 type UserType string
 
 const (
 	ADMIN        UserType = "admin"
-	PARTNERS     UserType = "partner"
+	PARTNERS     UserType = "partners"
 	WASTEWARRIOR UserType = "waste_warrior"
 )
 
+const (
+	UserActive   = "active"
+	UserInactive = "inactive"
+)
+
 type User struct {
-	Id          int64    `json:"id"`
+	Id       int64  `json:"id"`
+	FullName string `json:"fullname"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	// PasswordHash is the stored bcrypt hash. It is only loaded when looking
+	// a user up by email to check their credentials.
+	PasswordHash string   `json:"-"`
+	PhoneNumber  string   `json:"phone_number"`
+	UserType     UserType `json:"user_type"`
+	Status       string   `json:"status"`
+	// SuspendedAt, DeletedAt and PasswordResetRequiredAt are set by admins.
+	// Deleted users are kept so their history stays intact.
+	SuspendedAt             *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt               *time.Time `json:"deleted_at,omitempty"`
+	PasswordResetRequiredAt *time.Time `json:"password_reset_required_at,omitempty"`
+	DateCreated             time.Time
+	DateUpdated             time.Time
+}
+
+// UserDetails is a user account as admins see it.
+type UserDetails struct {
+	User    *User    `json:"user"`
+	Partner *Partner `json:"partner,omitempty"`
+}
+
+// AccessError returns why the user cannot use their account, or nil when
+// they can.
+func (u *User) AccessError() error {
+	switch {
+	case u.DeletedAt != nil:
+		return ErrUserNotFound
+	case u.SuspendedAt != nil:
+		return ErrAccountSuspended
+	case u.Status != UserActive:
+		return ErrInactiveAccount
+	case u.PasswordResetRequiredAt != nil:
+		return ErrPasswordReset
+	}
+	return nil
+}
+
+// Sells reports whether the user can still sell bags: a partner account that
+// has not been suspended or deleted. Admins act on users rather than partner
+// profiles, so this is checked wherever bags are offered to warriors.
+func (u *User) Sells() bool {
+	return u.UserType == PARTNERS && u.SuspendedAt == nil && u.DeletedAt == nil
+}
+
+// SignupRequest is the body of the signup endpoint. It is kept apart from
+// User so that the password can never be written back in a response.
+type SignupRequest struct {
 	FullName    string   `json:"fullname"`
 	Email       string   `json:"email"`
 	Password    string   `json:"password"`
 	PhoneNumber string   `json:"phone_number"`
 	UserType    UserType `json:"user_type"`
-	DateCreated time.Time
-	DateUpdated time.Time
-	wg          sync.WaitGroup
 }
 
+// VerificationCodeDigits is the length of the codes emailed to users.
+const VerificationCodeDigits = 6
+
+// TokenPurpose is what a code emailed to a user lets them do. A user has at
+// most one live code per purpose, and a code only works for its own.
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify_email"
+	PurposeResetPassword TokenPurpose = "reset_password"
+	PurposeChangeEmail   TokenPurpose = "change_email"
+	// PurposeLoginOTP is reserved for one-time sign-in codes, which nothing
+	// issues yet.
+	PurposeLoginOTP TokenPurpose = "login_otp"
+)
+
+// UserToken is a code emailed to a user. Only its HMAC is stored, and every
+// attempt to use it is counted.
 type UserToken struct {
-	Id          int64  `json:"id"`
-	UserID      int64  `json:"user_id"`
-	Email       string `json:"email"`
-	Token       int    `json:"token"`
+	Id      int64        `json:"id"`
+	UserID  int64        `json:"user_id"`
+	Purpose TokenPurpose `json:"purpose"`
+	// Email is the address the code was sent to, which for an email change
+	// is the new address.
+	Email    string `json:"email"`
+	CodeHash string `json:"-"`
+	Attempts int    `json:"-"`
+	// LockedUntil is set once the attempts run out. No code can be used or
+	// sent to the user before then.
+	LockedUntil *time.Time `json:"-"`
 	ExpireAt    time.Time
 	DateCreated time.Time
 	DateUpdated time.Time
 }
 
-func ValidateEmail(v *validator.Validator, email string) {
-	v.Check(email != "", "email", "must be provided")
-	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+// Locked reports whether attempts have run out and the lockout lasts past now.
+func (t *UserToken) Locked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
 }
 
-func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+// CarryAttempts gives t the attempts made on old, the code it replaces, and
+// any lockout still running at now. A lockout that has ended clears them.
+func (t *UserToken) CarryAttempts(old *UserToken, now time.Time) {
+	if old.LockedUntil != nil && !old.Locked(now) {
+		t.Attempts, t.LockedUntil = 0, nil
+		return
+	}
+	t.Attempts, t.LockedUntil = old.Attempts, old.LockedUntil
 }
 
-func ValidateUserType(v *validator.Validator, user_type UserType) {
-	v.Check(user_type != "", "user_type", "must be provided")
-	v.Check(user_type == "admin" || user_type == "partners" || user_type == "waste_warrior", "user_type", "The select user type is not the valid user type")
-
+// VerifyCodeRequest is the body of the email verification endpoint.
+type VerifyCodeRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
 }
 
-func ValidateUserData(v *validator.Validator, user *User) {
-	v.Check(user.FullName != "", "fullname", "must be provided")
-	v.Check(len(user.FullName) <= 500, "fullname", "must not be more than 500 bytes long")
-
-	ValidateEmail(v, user.Email)
-	ValidatePasswordPlaintext(v, user.Password)
-	ValidateUserType(v, user.UserType)
-	v.Check(user.PhoneNumber != "", "phone_number", "must be provided")
-	v.Check(len(user.PhoneNumber) <= 20, "phone_number", "must not be more than 20 bytes long")
-	//v.Check(user.UserType != "", "user_type", "must be provided")
-
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
 }
-func (u *User) Save() error {
-	query := `
-	INSERT INTO users (fullname, email, password, phone_number, user_type)
-	VALUES (?, ?, ?, ?, ?)
-	`
-	stmt, err := database.DB.Prepare(query)
-	if err != nil {
-		return err
-	}
 
-	defer stmt.Close()
-
-	hashPassword, _ := utility.HashPassword(u.Password)
-
-	result, err := stmt.Exec(u.FullName, u.Email, hashPassword, u.PhoneNumber, u.UserType)
-	if err != nil {
-		return err
-	}
-
-	id, err := result.LastInsertId()
-	u.Id = id
-
-	err = u.CreateToken()
-	if err != nil {
-		return err
-	}
-
-	fmt.Println(err)
-	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "ERROR 1062 (23000)") || strings.Contains(err.Error(), "email_UNIQUE"):
-			return ErrDuplicateEmail
-		default:
-			return err
-		}
-	}
-
-	return nil
+// ForgotPasswordRequest asks for a password reset code.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
 }
 
-func (u *User) CreateToken() error {
-	// Check if a token for the user already exists
-	query := "SELECT id FROM user_tokens WHERE user_id = ?"
-	row := database.DB.QueryRow(query, u.Id)
-
-	var id int64
-	err := row.Scan(&id)
-	if err != nil && err != sql.ErrNoRows {
-		return err
-	}
-
-	// If a token exists, delete it
-	if err != sql.ErrNoRows {
-		query = "DELETE FROM user_tokens WHERE id = ?"
-		_, err = database.DB.Exec(query, id)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Create a new token for the user
-	query = `
-	INSERT INTO user_tokens (user_id, email, token, expire_at)
-	VALUES (?, ?, ?, ?)
-	`
-
-	stmt, err := database.DB.Prepare(query)
-	if err != nil {
-		return err
-	}
-
-	defer stmt.Close()
-
-	rand.Seed(time.Now().UnixNano())
-	token := rand.Intn(7999) + 1000
-
-	expiredAt := time.Now().Add(3 * 24 * time.Hour)
-
-	userToken := UserToken{
-		UserID:   u.Id,
-		Email:    u.Email,
-		Token:    token,
-		ExpireAt: expiredAt,
-	}
-
-	u.background(func() {
-		data := map[string]interface{}{
-			"userName": u.FullName,
-			"email":    u.Email,
-			"Code":     userToken.Token,
-			"ExpireAt": expiredAt,
-		}
-
-		host := os.Getenv("MAIL_HOST")
-		portConv := os.Getenv("MAIL_PORT")
-		port, err := strconv.Atoi(portConv)
-		if err != nil {
-			return
-		}
-		mail := mailer.New(host, port, os.Getenv("MAIL_USERNAME"), os.Getenv("MAIL_PASSWORD"), os.Getenv("MAIL_SENDER"))
-		err = mail.Send(u.Email, "user_token.html", data)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-	})
-
-	result, err := stmt.Exec(userToken.UserID, userToken.Email, userToken.Token, userToken.ExpireAt)
-	if err != nil {
-		return err
-	}
-
-	id, err = result.LastInsertId()
-	userToken.Id = id
-
-	if err != nil {
-		return err
-	}
-	return nil
+// ResetPasswordRequest sets a new password with a password reset code.
+type ResetPasswordRequest struct {
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
 }
 
-func (u *User) background(fn func()) {
-	u.wg.Add(1)
-	go func() {
-		defer u.wg.Done()
-		// Recover any panic.
-		defer func() {
-			if err := recover(); err != nil {
-				_ = fmt.Errorf("%s", err)
-			}
-		}()
-
-		fn()
-	}()
+// ChangeEmailRequest asks for a code to be sent to a new address. The
+// current password is required so a stolen session cannot take the account.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
 }
 
-func (u *User) VerifyToken(token int) (bool, error) {
-	query := `
-    SELECT expire_at, user_id FROM user_tokens WHERE  token = ?
-    `
-	stmt, err := database.DB.Prepare(query)
-	if err != nil {
-		return false, err
-	}
-
-	defer stmt.Close()
-
-	var expireAt time.Time
-	var userId int64
-	err = stmt.QueryRow(token).Scan(&expireAt, &userId)
-	if err != nil {
-		return false, err
-	}
-
-	if userId == 0 {
-		return false, errors.New("token not found with user id")
-	}
-
-	fmt.Println(expireAt, userId)
-	expirationTime := expireAt.Add(3 * 24 * time.Hour)
-
-	if time.Now().After(expirationTime) {
-		return false, errors.New("token expired")
-	}
-
-	err = getUserWithIdAndUpdateFieldReturnUser(userId, "status", "active")
-	if err != nil {
-		return false, err
-	}
-	err = deleteUserToken(token)
-	if err != nil {
-		return false, err
-	}
-
-	return true, nil
+// ConfirmEmailChangeRequest switches to the new address with the code sent
+// to it.
+type ConfirmEmailChangeRequest struct {
+	Code string `json:"code"`
 }
 
-func deleteUserToken(token int) error {
-	query := `
-	DELETE FROM user_tokens WHERE token = ?
-	`
-	stmt, err := database.DB.Prepare(query)
-	if err != nil {
-		return err
-	}
-
-	defer stmt.Close()
+func ValidateVerifyCode(v *validator.Validator, req *VerifyCodeRequest) {
+	ValidateEmail(v, req.Email)
+	ValidateCode(v, req.Code)
+}
 
-	_, err = stmt.Exec(token)
-	if err != nil {
-		return err
-	}
+func ValidateCode(v *validator.Validator, code string) {
+	v.Check(len(code) == VerificationCodeDigits && validator.Matches(code, validator.DigitsRX), "code", fmt.Sprintf("must be %d digits", VerificationCodeDigits))
+}
 
-	return nil
+func ValidateResetPassword(v *validator.Validator, req *ResetPasswordRequest) {
+	ValidateEmail(v, req.Email)
+	ValidateCode(v, req.Code)
+	ValidatePasswordPlaintext(v, req.Password)
 }
 
-func getUserWithIdAndUpdateFieldReturnUser(userId int64, field string, value string) error {
-	query := fmt.Sprintf("UPDATE users SET %s = ? WHERE id = ?", field)
-	stmt, err := database.DB.Prepare(query)
-	if err != nil {
-		return err
-	}
+func ValidateChangeEmail(v *validator.Validator, req *ChangeEmailRequest) {
+	v.Check(req.NewEmail != "", "new_email", "must be provided")
+	v.Check(validator.Matches(req.NewEmail, validator.EmailRX), "new_email", "must be a valid email address")
+	v.Check(req.Password != "", "password", "must be provided")
+}
 
-	defer stmt.Close()
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
 
-	_, err = stmt.Exec(value, userId)
-	if err != nil {
-		return err
-	}
+func ValidateUserType(v *validator.Validator, user_type UserType) {
+	v.Check(user_type != "", "user_type", "must be provided")
+	v.Check(user_type == ADMIN || user_type == PARTNERS || user_type == WASTEWARRIOR, "user_type", "The select user type is not the valid user type")
 
-	return nil
 }
 
-func (u *User) CheckUserWithEmailExists(email string) (bool, error) {
-	query := "SELECT id FROM users WHERE email = ?"
-	row := database.DB.QueryRow(query, email)
-
-	var id int64
-	err := row.Scan(&id)
-	if err != nil {
-		return false, err
-	}
+func ValidateSignup(v *validator.Validator, req *SignupRequest) {
+	v.Check(req.FullName != "", "fullname", "must be provided")
+	v.Check(len(req.FullName) <= 500, "fullname", "must not be more than 500 bytes long")
 
-	return true, nil
+	ValidateEmail(v, req.Email)
+	ValidatePasswordPlaintext(v, req.Password)
+	ValidateUserType(v, req.UserType)
+	v.Check(req.UserType != ADMIN, "user_type", "admin accounts can not be self registered")
+	v.Check(req.PhoneNumber != "", "phone_number", "must be provided")
+	v.Check(len(req.PhoneNumber) <= 20, "phone_number", "must not be more than 20 bytes long")
+	//v.Check(user.UserType != "", "user_type", "must be provided")
 
 }
-
-//func getUserWithAnd(userId int64) (*User, error) {
-//	query := "SELECT id, fullname, email, phone_number, user_type FROM users WHERE id = ?"
-//	row := database.DB.QueryRow(query, userId)
-//
-//	var user User
-//	err := row.Scan(&user.Id, &user.FullName, &user.Email, &user.PhoneNumber, &user.UserType)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return &user, nil
-//}
-
-//func (u *User) ValidateUserCredential() error {
-//	query := "SELECT id,password FROM users WHERE email=?"
-//
-//	row := database.DB.QueryRow(query, u.Email)
-//
-//	var retrievedPassword string
-//	err := row.Scan(&u.ID, &retrievedPassword)
-//	if err != nil {
-//		return errors.New("credential invalid")
-//	}
-//
-//	passwordIsValid := utility.CompareHashedPassword(u.Password, retrievedPassword)
-//
-//	if !passwordIsValid {
-//		return errors.New("credential invalid")
-//	}
-//
-//	return nil
-//
-//}
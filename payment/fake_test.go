@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeProviderCaptureAndRefund(t *testing.T) {
+	ctx := context.Background()
+	provider := NewFakeProvider("test-secret")
+
+	authorized, err := provider.Authorize(ctx, AuthorizeRequest{Amount: 10, Source: "tok_visa"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if authorized.Status != StatusAuthorized || authorized.Reference == "" {
+		t.Fatalf("Authorize: got %+v", authorized)
+	}
+
+	_, err = provider.Capture(ctx, authorized.Reference, 11)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Capture more than authorized: got %v, want ErrInvalidState", err)
+	}
+	captured, err := provider.Capture(ctx, authorized.Reference, 10)
+	if err != nil || captured.Status != StatusCaptured {
+		t.Fatalf("Capture: got %+v, %v", captured, err)
+	}
+	_, err = provider.Capture(ctx, authorized.Reference, 10)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Capture twice: got %v, want ErrInvalidState", err)
+	}
+
+	_, err = provider.Refund(ctx, authorized.Reference, 11)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Refund more than captured: got %v, want ErrInvalidState", err)
+	}
+	refunded, err := provider.Refund(ctx, authorized.Reference, 10)
+	if err != nil || refunded.Status != StatusRefunded {
+		t.Fatalf("Refund: got %+v, %v", refunded, err)
+	}
+	_, err = provider.Refund(ctx, authorized.Reference, 10)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Refund twice: got %v, want ErrInvalidState", err)
+	}
+
+	_, err = provider.Capture(ctx, "fake_unknown", 10)
+	if !errors.Is(err, ErrUnknownPayment) {
+		t.Fatalf("Capture unknown payment: got %v, want ErrUnknownPayment", err)
+	}
+}
+
+func TestFakeProviderDeclines(t *testing.T) {
+	ctx := context.Background()
+	provider := NewFakeProvider("test-secret")
+
+	declined, err := provider.Authorize(ctx, AuthorizeRequest{Amount: 10, Source: DeclineSource})
+	if !errors.Is(err, ErrDeclined) {
+		t.Fatalf("Authorize declined card: got %v, want ErrDeclined", err)
+	}
+	if declined == nil || declined.Status != StatusFailed {
+		t.Fatalf("Authorize declined card: got %+v, want a failed payment", declined)
+	}
+	_, err = provider.Capture(ctx, declined.Reference, 10)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Capture declined payment: got %v, want ErrInvalidState", err)
+	}
+
+	_, err = provider.Authorize(ctx, AuthorizeRequest{Amount: 0, Source: "tok_visa"})
+	if !errors.Is(err, ErrDeclined) {
+		t.Fatalf("Authorize nothing: got %v, want ErrDeclined", err)
+	}
+
+	// Refunding an uncaptured authorization releases the hold.
+	authorized, err := provider.Authorize(ctx, AuthorizeRequest{Amount: 10, Source: "tok_visa"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	released, err := provider.Refund(ctx, authorized.Reference, 10)
+	if err != nil || released.Status != StatusRefunded {
+		t.Fatalf("Refund authorization: got %+v, %v", released, err)
+	}
+}
+
+func TestFakeProviderVerifyWebhook(t *testing.T) {
+	provider := NewFakeProvider("test-secret")
+	payload := []byte(`{"reference":"fake_000001","status":"captured"}`)
+
+	event, err := provider.VerifyWebhook(payload, provider.Sign(payload))
+	if err != nil {
+		t.Fatalf("VerifyWebhook: %v", err)
+	}
+	if event.Reference != "fake_000001" || event.Status != StatusCaptured {
+		t.Fatalf("VerifyWebhook: got %+v", event)
+	}
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+	}{
+		{"no signature", payload, ""},
+		{"tampered payload", []byte(`{"reference":"fake_000001","status":"refunded"}`), provider.Sign(payload)},
+		{"other secret", payload, NewFakeProvider("other-secret").Sign(payload)},
+	}
+	for _, tt := range tests {
+		_, err := provider.VerifyWebhook(tt.payload, tt.signature)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+}
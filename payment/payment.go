@@ -0,0 +1,55 @@
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrDeclined         = errors.New("payment declined")
+	ErrUnknownPayment   = errors.New("unknown payment reference")
+	ErrInvalidState     = errors.New("payment is not in a state that allows this operation")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Status mirrors the payment_status column on transactions.
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusRefunded   Status = "refunded"
+	StatusFailed     Status = "failed"
+)
+
+// AuthorizeRequest places a hold for Amount on the card identified by
+// Source, which is whatever token the client obtained from the gateway.
+type AuthorizeRequest struct {
+	Amount      float64
+	Currency    string
+	Source      string
+	Description string
+}
+
+// Result is what the gateway reports after an operation.
+type Result struct {
+	Reference string
+	Status    Status
+}
+
+// WebhookEvent is a verified asynchronous status update from the gateway.
+type WebhookEvent struct {
+	Reference string `json:"reference"`
+	Status    Status `json:"status"`
+}
+
+// PaymentProvider is implemented by every payment gateway the API can talk to.
+type PaymentProvider interface {
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Result, error)
+	Capture(ctx context.Context, reference string, amount float64) (*Result, error)
+	Refund(ctx context.Context, reference string, amount float64) (*Result, error)
+	// VerifyWebhook checks the signature header sent with a webhook payload
+	// and returns the decoded event.
+	VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
@@ -0,0 +1,57 @@
+// Package buildinfo reports which build of the API is running.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Commit and BuildTime are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/horlathunbhosun/reducing-food-waste/pkg/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// When Commit is left empty, the revision the Go toolchain embeds in the
+// binary is used instead.
+var (
+	Commit    string
+	BuildTime string
+)
+
+type Info struct {
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified"`
+	BuildTime  string `json:"build_time"`
+	GoVersion  string `json:"go_version"`
+}
+
+func Get() Info {
+	info := Info{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				info.CommitTime = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -1,9 +1,35 @@
 package response
 
 type JsonResponse struct {
-	Error        bool        `json:"error"`
-	ErrorMessage interface{} `json:"error_message,omitempty"`
-	Message      string      `json:"message"`
-	Data         interface{} `json:"data,omitempty"`
-	Status       bool        `json:"status"`
+	Error   bool        `json:"error"`
+	Code    string      `json:"code,omitempty"`
+	Message string      `json:"message"`
+	Detail  string      `json:"detail,omitempty"`
+	Errors  interface{} `json:"errors,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	// Pagination describes the page of Data a list endpoint returned.
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Status     bool        `json:"status"`
+	RequestID  string      `json:"request_id,omitempty"`
+}
+
+// Pagination tells a client where it is in a list. NextCursor is left out on
+// the last page.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Problem is an error in the RFC 7807 application/problem+json format, with
+// the error code, field errors and request id as extension members.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Code      string      `json:"code"`
+	Errors    interface{} `json:"errors,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
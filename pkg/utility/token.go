@@ -0,0 +1,91 @@
+package utility
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// AccessClaims is the payload carried by the signed access token.
+type AccessClaims struct {
+	Email    string `json:"email"`
+	UserType string `json:"user_type"`
+	jwt.RegisteredClaims
+}
+
+// GenerateAccessToken signs a short-lived HS256 token for the given user that
+// expires after ttl.
+func GenerateAccessToken(secret []byte, ttl time.Duration, userId int64, email, userType string) (string, error) {
+	now := time.Now()
+	claims := AccessClaims{
+		Email:    email,
+		UserType: userType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userId, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// VerifyAccessToken checks the signature and expiry of an access token and
+// returns its claims.
+func VerifyAccessToken(secret []byte, tokenStr string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidAccessToken
+	}
+
+	return claims, nil
+}
+
+// GenerateRefreshToken returns a random opaque token together with the hash
+// that should be persisted in its place.
+func GenerateRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	plain := hex.EncodeToString(b)
+	return plain, HashToken(plain), nil
+}
+
+// GenerateCode returns a random code of the given number of decimal digits,
+// with leading zeros.
+func GenerateCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// HashCode returns the hex encoded HMAC-SHA256 of a short code sent to an
+// email address. Unlike a plain hash, it cannot be reversed by trying every
+// code without the secret.
+func HashCode(secret []byte, email, code string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(email + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+)
+
+type AuditLogRepository struct {
+	s *store
+}
+
+func (r *AuditLogRepository) List(ctx context.Context, q listing.Query) ([]*models.AuditEntry, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	entries := make([]*models.AuditEntry, 0, len(r.s.auditLog))
+	for _, entry := range r.s.auditLog {
+		found := *entry
+		entries = append(entries, &found)
+	}
+
+	entries, page := listing.Apply(entries, q, models.AuditListing)
+	return entries, page, nil
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+// FeedbackRepository works the partner rating aggregate out from the stored
+// feedback on every read instead of keeping a separate table in step.
+type FeedbackRepository struct {
+	s *store
+}
+
+func (r *FeedbackRepository) Create(ctx context.Context, feedback *models.Feedback) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.feedback {
+		if existing.TransactionID == feedback.TransactionID {
+			return models.ErrFeedbackExists
+		}
+	}
+
+	feedback.Id = r.s.id()
+	feedback.DateCreated = time.Now()
+	feedback.DateUpdated = feedback.DateCreated
+
+	stored := *feedback
+	r.s.feedback[feedback.Id] = &stored
+	return nil
+}
+
+func (r *FeedbackRepository) Update(ctx context.Context, feedback *models.Feedback) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.feedback[feedback.Id]
+	if !ok {
+		return models.ErrFeedbackNotFound
+	}
+
+	stored.Rating = feedback.Rating
+	stored.Comment = feedback.Comment
+	stored.DateUpdated = time.Now()
+	return nil
+}
+
+func (r *FeedbackRepository) Delete(ctx context.Context, feedback *models.Feedback) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.feedback[feedback.Id]; !ok {
+		return models.ErrFeedbackNotFound
+	}
+	delete(r.s.feedback, feedback.Id)
+	return nil
+}
+
+func (r *FeedbackRepository) GetByID(ctx context.Context, id int64) (*models.Feedback, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	f, ok := r.s.feedback[id]
+	if !ok {
+		return nil, models.ErrFeedbackNotFound
+	}
+
+	found := *f
+	return &found, nil
+}
+
+func (r *FeedbackRepository) GetByTransaction(ctx context.Context, transactionId int64) (*models.Feedback, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, f := range r.s.feedback {
+		if f.TransactionID == transactionId {
+			found := *f
+			return &found, nil
+		}
+	}
+	return nil, models.ErrFeedbackNotFound
+}
+
+func (r *FeedbackRepository) ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Feedback, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	feedback := []*models.Feedback{}
+	for _, f := range r.s.feedback {
+		if f.PartnerID == partnerId {
+			found := *f
+			feedback = append(feedback, &found)
+		}
+	}
+
+	feedback, page := listing.Apply(feedback, q, models.FeedbackListing)
+	return feedback, page, nil
+}
+
+func (r *FeedbackRepository) PartnerRating(ctx context.Context, partnerId int64) (*models.PartnerRating, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rating := &models.PartnerRating{
+		PartnerID:    partnerId,
+		Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+
+	sum := 0
+	for _, f := range r.s.feedback {
+		if f.PartnerID == partnerId {
+			rating.Count++
+			rating.Distribution[f.Rating]++
+			sum += f.Rating
+		}
+	}
+	if rating.Count > 0 {
+		rating.Average = float64(sum) / float64(rating.Count)
+	}
+
+	return rating, nil
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+type MagicBagRepository struct {
+	s *store
+}
+
+func (r *MagicBagRepository) Create(ctx context.Context, bag *models.MagicBag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	bag.ID = r.s.id()
+	bag.DateCreated = time.Now()
+	bag.DateUpdated = bag.DateCreated
+	r.s.setItems(bag)
+
+	r.s.magicBags[bag.ID] = copyMagicBag(bag, true)
+	return nil
+}
+
+func (r *MagicBagRepository) Update(ctx context.Context, bag *models.MagicBag, changes *models.MagicBagChanges) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.magicBags[bag.ID]
+	if !ok || stored.PartnerID != bag.PartnerID {
+		return nil
+	}
+
+	changes.Apply(stored)
+	stored.DateUpdated = time.Now()
+	if changes.Items != nil {
+		r.s.setItems(bag)
+		stored.Items = append([]models.MagicBagItem{}, bag.Items...)
+	}
+	return nil
+}
+
+func (r *MagicBagRepository) Withdraw(ctx context.Context, bag *models.MagicBag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if stored, ok := r.s.magicBags[bag.ID]; ok && stored.PartnerID == bag.PartnerID {
+		stored.Status = models.MagicBagWithdrawn
+		stored.DateUpdated = time.Now()
+	}
+	bag.Status = models.MagicBagWithdrawn
+	return nil
+}
+
+func (r *MagicBagRepository) GetByID(ctx context.Context, id int64) (*models.MagicBag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	bag, ok := r.s.magicBags[id]
+	if !ok {
+		return nil, models.ErrMagicBagNotFound
+	}
+	return r.s.labelledMagicBag(bag), nil
+}
+
+func (r *MagicBagRepository) Label(ctx context.Context, bags ...*models.MagicBag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, bag := range bags {
+		if stored, ok := r.s.magicBags[bag.ID]; ok {
+			labelled := r.s.labelledMagicBag(stored)
+			bag.Allergens, bag.DietaryTags = labelled.Allergens, labelled.DietaryTags
+		}
+	}
+	return nil
+}
+
+func (r *MagicBagRepository) Items(ctx context.Context, bagId int64) ([]models.MagicBagItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	items := []models.MagicBagItem{}
+	if bag, ok := r.s.magicBags[bagId]; ok {
+		items = append(items, bag.Items...)
+	}
+	return items, nil
+}
+
+func (r *MagicBagRepository) ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.MagicBag, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	bags, page := r.s.listMagicBags(q, func(bag *models.MagicBag) bool {
+		return bag.PartnerID == partnerId
+	})
+	return bags, page, nil
+}
+
+func (r *MagicBagRepository) ListAvailable(ctx context.Context, q listing.Query, now time.Time) ([]*models.MagicBag, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	bags, page := r.s.listMagicBags(q, func(bag *models.MagicBag) bool {
+		partner, ok := r.s.partners[bag.PartnerID]
+		if !ok || partner.Status != models.PartnerApproved {
+			return false
+		}
+		seller, ok := r.s.users[partner.UserID]
+		return bag.Status == models.MagicBagActive && bag.Quantity > 0 && !bag.PickupEnded(now) && ok && seller.Sells()
+	})
+	return bags, page, nil
+}
+
+// listMagicBags returns the page of the bags matching keep that q asks for,
+// labelled but without their items.
+func (s *store) listMagicBags(q listing.Query, keep func(*models.MagicBag) bool) ([]*models.MagicBag, listing.Page) {
+	bags := []*models.MagicBag{}
+	for _, bag := range s.magicBags {
+		if keep(bag) {
+			bags = append(bags, s.labelledMagicBag(bag))
+		}
+	}
+
+	return listing.Apply(bags, q, models.MagicBagListing)
+}
+
+// labelledMagicBag copies the stored bag without its items, labelled from
+// the products in them.
+func (s *store) labelledMagicBag(bag *models.MagicBag) *models.MagicBag {
+	products := make([]*models.Product, 0, len(bag.Items))
+	for _, item := range bag.Items {
+		if product, ok := s.products[item.ProductID]; ok {
+			products = append(products, product)
+		}
+	}
+
+	c := copyMagicBag(bag, false)
+	c.Label(products)
+	return c
+}
+
+// setItems gives every item of the bag a fresh id.
+func (s *store) setItems(bag *models.MagicBag) {
+	for i := range bag.Items {
+		bag.Items[i].ID = s.id()
+		bag.Items[i].MagicBagID = bag.ID
+	}
+}
+
+func copyMagicBag(bag *models.MagicBag, withItems bool) *models.MagicBag {
+	c := *bag
+	c.Items = nil
+	if withItems {
+		c.Items = append([]models.MagicBagItem{}, bag.Items...)
+	}
+	return &c
+}
@@ -0,0 +1,61 @@
+// Package memory implements the repositories with maps guarded by a single
+// mutex. It enforces the same constraints as the database schema, so the API
+// behaves the same against it, but nothing survives a restart.
+package memory
+
+import (
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"sync"
+)
+
+// store holds every table. Repositories hand out copies of the stored rows so
+// callers can never change them without going through a repository.
+type store struct {
+	mu     sync.Mutex
+	nextID int64
+
+	users         map[int64]*models.User
+	userTokens    map[int64]*models.UserToken
+	refreshTokens map[int64]*models.RefreshToken
+	partners      map[int64]*models.Partner
+	products      map[int64]*models.Product
+	magicBags     map[int64]*models.MagicBag
+	transactions  map[int64]*models.Transaction
+	feedback      map[int64]*models.Feedback
+	auditLog      map[int64]*models.AuditEntry
+}
+
+// New returns every repository backed by one shared, empty store.
+func New() repository.Repositories {
+	s := &store{
+		users:         make(map[int64]*models.User),
+		userTokens:    make(map[int64]*models.UserToken),
+		refreshTokens: make(map[int64]*models.RefreshToken),
+		partners:      make(map[int64]*models.Partner),
+		products:      make(map[int64]*models.Product),
+		magicBags:     make(map[int64]*models.MagicBag),
+		transactions:  make(map[int64]*models.Transaction),
+		feedback:      make(map[int64]*models.Feedback),
+		auditLog:      make(map[int64]*models.AuditEntry),
+	}
+
+	return repository.Repositories{
+		Users:         &UserRepository{s},
+		UserTokens:    &UserTokenRepository{s},
+		RefreshTokens: &RefreshTokenRepository{s},
+		Partners:      &PartnerRepository{s},
+		Products:      &ProductRepository{s},
+		MagicBags:     &MagicBagRepository{s},
+		Transactions:  &TransactionRepository{s},
+		Feedback:      &FeedbackRepository{s},
+		AuditLog:      &AuditLogRepository{s},
+	}
+}
+
+// id returns the next row id. Ids are unique across tables, which the
+// callers do not mind and which keeps them increasing in insertion order.
+func (s *store) id() int64 {
+	s.nextID++
+	return s.nextID
+}
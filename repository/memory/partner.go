@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+type PartnerRepository struct {
+	s *store
+}
+
+func (r *PartnerRepository) Create(ctx context.Context, partner *models.Partner) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.partners {
+		if existing.UserID == partner.UserID {
+			return models.ErrPartnerExists
+		}
+	}
+
+	partner.ID = r.s.id()
+	partner.DateCreated = time.Now()
+	partner.DateUpdated = partner.DateCreated
+
+	stored := *partner
+	r.s.partners[partner.ID] = &stored
+	return nil
+}
+
+func (r *PartnerRepository) Update(ctx context.Context, partner *models.Partner) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.partners[partner.ID]
+	if !ok {
+		return nil
+	}
+	stored.BRNumber = partner.BRNumber
+	stored.Logo = partner.Logo
+	stored.Address = partner.Address
+	stored.Timezone = partner.Timezone
+	stored.Status = partner.Status
+	stored.RejectionReason = partner.RejectionReason
+	stored.ReviewedAt = partner.ReviewedAt
+	stored.DateUpdated = time.Now()
+	return nil
+}
+
+func (r *PartnerRepository) GetByID(ctx context.Context, id int64) (*models.Partner, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	partner, ok := r.s.partners[id]
+	if !ok {
+		return nil, models.ErrPartnerNotFound
+	}
+
+	found := *partner
+	return &found, nil
+}
+
+func (r *PartnerRepository) GetByUserID(ctx context.Context, userId int64) (*models.Partner, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, partner := range r.s.partners {
+		if partner.UserID == userId {
+			found := *partner
+			return &found, nil
+		}
+	}
+	return nil, models.ErrPartnerNotFound
+}
+
+func (r *PartnerRepository) List(ctx context.Context, q listing.Query) ([]*models.Partner, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	partners := []*models.Partner{}
+	for _, partner := range r.s.partners {
+		found := *partner
+		partners = append(partners, &found)
+	}
+
+	partners, page := listing.Apply(partners, q, models.PartnerListing)
+	return partners, page, nil
+}
+
+func (r *PartnerRepository) Review(ctx context.Context, partner *models.Partner, status models.PartnerStatus, reason string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.partners[partner.ID]
+	if !ok || stored.Status != models.PartnerPending {
+		return models.ErrPartnerAlreadyReview
+	}
+
+	stored.Status = status
+	stored.RejectionReason = reason
+	stored.ReviewedAt = &at
+	stored.DateUpdated = time.Now()
+
+	partner.Status = status
+	partner.RejectionReason = reason
+	partner.ReviewedAt = &at
+	return nil
+}
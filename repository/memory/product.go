@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+type ProductRepository struct {
+	s *store
+}
+
+func (r *ProductRepository) Create(ctx context.Context, product *models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	product.Id = r.s.id()
+	product.DateCreated = time.Now()
+	product.DateUpdated = product.DateCreated
+
+	r.s.products[product.Id] = copyProduct(product)
+	return nil
+}
+
+func (r *ProductRepository) Update(ctx context.Context, product *models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.products[product.Id]
+	if !ok {
+		return nil
+	}
+
+	updated := copyProduct(product)
+	updated.PartnerID = stored.PartnerID
+	updated.DateCreated = stored.DateCreated
+	updated.DateUpdated = time.Now()
+	r.s.products[product.Id] = updated
+	return nil
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.products[id]; !ok {
+		return models.ErrProductNotFound
+	}
+
+	for _, bag := range r.s.magicBags {
+		for _, item := range bag.Items {
+			if item.ProductID == id {
+				return models.ErrProductInUse
+			}
+		}
+	}
+
+	delete(r.s.products, id)
+	return nil
+}
+
+func (r *ProductRepository) GetByID(ctx context.Context, id int64) (*models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	product, ok := r.s.products[id]
+	if !ok {
+		return nil, models.ErrProductNotFound
+	}
+	return copyProduct(product), nil
+}
+
+func (r *ProductRepository) List(ctx context.Context, q listing.Query) ([]*models.Product, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products, page := r.s.listProducts(q, func(product *models.Product) bool {
+		if product.PartnerID == nil {
+			return true
+		}
+		partner, ok := r.s.partners[*product.PartnerID]
+		return ok && partner.Status == models.PartnerApproved
+	})
+	return products, page, nil
+}
+
+func (r *ProductRepository) ListUsable(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Product, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	products, page := r.s.listProducts(q, func(product *models.Product) bool {
+		return usable(product, partnerId)
+	})
+	return products, page, nil
+}
+
+func (r *ProductRepository) Exist(ctx context.Context, partnerId int64, ids []int64) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, id := range ids {
+		product, ok := r.s.products[id]
+		if !ok || !usable(product, partnerId) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *store) listProducts(q listing.Query, keep func(*models.Product) bool) ([]*models.Product, listing.Page) {
+	products := []*models.Product{}
+	for _, product := range s.products {
+		if keep(product) {
+			products = append(products, copyProduct(product))
+		}
+	}
+
+	return listing.Apply(products, q, models.ProductListing)
+}
+
+// usable reports whether the partner can put the product in their bags.
+func usable(product *models.Product, partnerId int64) bool {
+	return product.PartnerID == nil || *product.PartnerID == partnerId
+}
+
+// copyProduct copies the product with its labels in the order they are
+// reported in.
+func copyProduct(product *models.Product) *models.Product {
+	c := *product
+	if product.PartnerID != nil {
+		partnerId := *product.PartnerID
+		c.PartnerID = &partnerId
+	}
+	c.SortLabels()
+	return &c
+}
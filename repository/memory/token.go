@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+type UserTokenRepository struct {
+	s *store
+}
+
+func (r *UserTokenRepository) Replace(ctx context.Context, token *models.UserToken, now time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token.Attempts, token.LockedUntil = 0, nil
+	for id, existing := range r.s.userTokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose {
+			token.CarryAttempts(existing, now)
+			delete(r.s.userTokens, id)
+		}
+	}
+
+	token.Id = r.s.id()
+	token.DateCreated = time.Now()
+	token.DateUpdated = token.DateCreated
+
+	stored := *token
+	r.s.userTokens[token.Id] = &stored
+	return nil
+}
+
+func (r *UserTokenRepository) GetByUserID(ctx context.Context, userId int64, purpose models.TokenPurpose) (*models.UserToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.userTokens {
+		if existing.UserID == userId && existing.Purpose == purpose {
+			found := *existing
+			return &found, nil
+		}
+	}
+	return nil, models.ErrTokenNotFound
+}
+
+func (r *UserTokenRepository) RecordAttempt(ctx context.Context, id int64, maxAttempts int, lockUntil time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token, ok := r.s.userTokens[id]
+	if !ok || token.Attempts >= maxAttempts {
+		return false, nil
+	}
+
+	token.Attempts++
+	if token.Attempts >= maxAttempts {
+		token.LockedUntil = &lockUntil
+	}
+	token.DateUpdated = time.Now()
+	return true, nil
+}
+
+func (r *UserTokenRepository) Delete(ctx context.Context, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.userTokens, id)
+	return nil
+}
+
+type RefreshTokenRepository struct {
+	s *store
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token.Id = r.s.id()
+	token.DateCreated = time.Now()
+	token.DateUpdated = token.DateCreated
+
+	stored := *token
+	r.s.refreshTokens[token.Id] = &stored
+	return nil
+}
+
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, token := range r.s.refreshTokens {
+		if token.TokenHash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, models.ErrInvalidRefreshToken
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id int64, at time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token, ok := r.s.refreshTokens[id]
+	if !ok || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.RevokedAt = &at
+	return true, nil
+}
+
+func (r *RefreshTokenRepository) RevokeByHash(ctx context.Context, hash string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, token := range r.s.refreshTokens {
+		if token.TokenHash == hash && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userId int64, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, token := range r.s.refreshTokens {
+		if token.UserID == userId && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
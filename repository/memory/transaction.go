@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"time"
+)
+
+type TransactionRepository struct {
+	s *store
+}
+
+func (r *TransactionRepository) Reserve(ctx context.Context, userId, bagId int64, paymentType models.PaymentType, now time.Time) (*models.Transaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[userId]; !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	bag, ok := r.s.magicBags[bagId]
+	if !ok {
+		return nil, models.ErrMagicBagNotFound
+	}
+	if bag.Status != models.MagicBagActive {
+		return nil, models.ErrMagicBagWithdrawn
+	}
+	if bag.PickupEnded(now) {
+		return nil, models.ErrPickupEnded
+	}
+	if bag.Quantity <= 0 {
+		return nil, models.ErrMagicBagSoldOut
+	}
+
+	partner, ok := r.s.partners[bag.PartnerID]
+	if !ok || partner.Status != models.PartnerApproved {
+		return nil, models.ErrMagicBagNotFound
+	}
+	seller, ok := r.s.users[partner.UserID]
+	if !ok || !seller.Sells() {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	purchaseDay := now.In(partner.Location()).Format(time.DateOnly)
+
+	// Failed and refunded purchases do not count towards the limit.
+	for _, t := range r.s.transactions {
+		if t.UserID == userId && t.PartnerID == partner.ID && t.PurchaseDay == purchaseDay &&
+			t.PaymentStatus != payment.StatusFailed && t.PaymentStatus != payment.StatusRefunded {
+			return nil, models.ErrDailyPurchaseLimit
+		}
+	}
+
+	bag.Quantity--
+
+	transaction := &models.Transaction{
+		Id:            r.s.id(),
+		Amount:        bag.BagPrice,
+		PaymentType:   paymentType,
+		PaymentStatus: payment.StatusPending,
+		PurchaseDay:   purchaseDay,
+		DateCreated:   now,
+		DateUpdated:   now,
+		UserID:        userId,
+		PartnerID:     partner.ID,
+		MagicBagID:    bag.ID,
+	}
+
+	stored := *transaction
+	r.s.transactions[transaction.Id] = &stored
+	return transaction, nil
+}
+
+func (r *TransactionRepository) UpdatePayment(ctx context.Context, transaction *models.Transaction, from payment.Status, releaseBag bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.transactions[transaction.Id]
+	if !ok || stored.PaymentStatus != from {
+		return models.ErrPaymentState
+	}
+
+	if transaction.PaymentReference != "" {
+		for _, t := range r.s.transactions {
+			if t.Id != stored.Id && t.PaymentReference == transaction.PaymentReference {
+				return models.ErrPaymentState
+			}
+		}
+	}
+
+	stored.PaymentStatus = transaction.PaymentStatus
+	stored.PaymentReference = transaction.PaymentReference
+	stored.PickedUpAt = transaction.PickedUpAt
+	stored.DateUpdated = time.Now()
+
+	if releaseBag {
+		if bag, ok := r.s.magicBags[stored.MagicBagID]; ok {
+			bag.Quantity++
+		}
+	}
+	return nil
+}
+
+func (r *TransactionRepository) GetByID(ctx context.Context, id int64) (*models.Transaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.transactions[id]
+	if !ok {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	found := *t
+	return &found, nil
+}
+
+func (r *TransactionRepository) GetByReference(ctx context.Context, reference string) (*models.Transaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, t := range r.s.transactions {
+		if reference != "" && t.PaymentReference == reference {
+			found := *t
+			return &found, nil
+		}
+	}
+	return nil, models.ErrTransactionNotFound
+}
+
+func (r *TransactionRepository) ListByUser(ctx context.Context, userId int64, q listing.Query) ([]*models.Transaction, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	transactions := []*models.Transaction{}
+	for _, t := range r.s.transactions {
+		if t.UserID == userId {
+			found := *t
+			transactions = append(transactions, &found)
+		}
+	}
+
+	transactions, page := listing.Apply(transactions, q, models.TransactionListing)
+	return transactions, page, nil
+}
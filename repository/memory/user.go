@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"strings"
+	"time"
+)
+
+type UserRepository struct {
+	s *store
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.create(user)
+}
+
+func (r *UserRepository) Register(ctx context.Context, user *models.User, token *models.UserToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	err := r.create(user)
+	if err != nil {
+		return err
+	}
+
+	token.Id = r.s.id()
+	token.UserID = user.Id
+	token.DateCreated = user.DateCreated
+	token.DateUpdated = user.DateCreated
+
+	stored := *token
+	r.s.userTokens[token.Id] = &stored
+	return nil
+}
+
+// create stores the user. The caller holds the lock.
+func (r *UserRepository) create(user *models.User) error {
+	for _, existing := range r.s.users {
+		if strings.EqualFold(existing.Email, user.Email) {
+			return models.ErrDuplicateEmail
+		}
+		if existing.PhoneNumber == user.PhoneNumber {
+			return models.ErrDuplicatePhone
+		}
+	}
+
+	user.Id = r.s.id()
+	user.Status = models.UserInactive
+	user.DateCreated = time.Now()
+	user.DateUpdated = user.DateCreated
+
+	stored := *user
+	stored.Password = ""
+	r.s.users[user.Id] = &stored
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	found := *user
+	found.PasswordHash = ""
+	return &found, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, user := range r.s.users {
+		if strings.EqualFold(user.Email, email) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if user, ok := r.s.users[id]; ok {
+		user.Status = status
+		user.DateUpdated = time.Now()
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if user, ok := r.s.users[id]; ok {
+		user.PasswordHash = passwordHash
+		user.PasswordResetRequiredAt = nil
+		user.DateUpdated = time.Now()
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateEmail(ctx context.Context, id int64, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.users {
+		if existing.Id != id && strings.EqualFold(existing.Email, email) {
+			return models.ErrDuplicateEmail
+		}
+	}
+
+	if user, ok := r.s.users[id]; ok {
+		user.Email = email
+		user.DateUpdated = time.Now()
+	}
+	return nil
+}
+
+func (r *UserRepository) List(ctx context.Context, q listing.Query) ([]*models.User, listing.Page, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	users := make([]*models.User, 0, len(r.s.users))
+	for _, user := range r.s.users {
+		found := *user
+		found.PasswordHash = ""
+		users = append(users, &found)
+	}
+
+	users, page := listing.Apply(users, q, models.UserListing)
+	return users, page, nil
+}
+
+func (r *UserRepository) UpdateAccount(ctx context.Context, user *models.User, entry *models.AuditEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[user.Id]
+	if !ok || stored.DeletedAt != nil {
+		return models.ErrUserDeleted
+	}
+
+	stored.UserType = user.UserType
+	stored.SuspendedAt = user.SuspendedAt
+	stored.DeletedAt = user.DeletedAt
+	stored.PasswordResetRequiredAt = user.PasswordResetRequiredAt
+	stored.DateUpdated = time.Now()
+
+	entry.Id = r.s.id()
+	entry.DateCreated = stored.DateUpdated
+
+	recorded := *entry
+	r.s.auditLog[entry.Id] = &recorded
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package repository defines the storage interfaces the services depend on.
+// The sqlstore package implements them on top of MySQL and the memory package
+// keeps everything in process, so the API can run without a database.
+//
+// List methods return the page of rows a listing.Query asks for, using the
+// fields of the matching spec in the models package.
+//
+// Implementations report missing rows and constraint violations with the
+// sentinel errors from the models package, so callers never need to know
+// which store they are talking to.
+package repository
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"time"
+)
+
+type UserRepository interface {
+	// Create stores a new user whose PasswordHash is already set. A taken
+	// email returns models.ErrDuplicateEmail and a taken phone number
+	// models.ErrDuplicatePhone.
+	Create(ctx context.Context, user *models.User) error
+	// Register creates the user like Create and stores token for them in the
+	// same step, so that neither is kept without the other.
+	Register(ctx context.Context, user *models.User, token *models.UserToken) error
+	GetByID(ctx context.Context, id int64) (*models.User, error)
+	// GetByEmail also loads the password hash so credentials can be checked.
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	// UpdatePassword also lifts a password reset an admin required.
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	// UpdateEmail returns models.ErrDuplicateEmail when another user has the
+	// email.
+	UpdateEmail(ctx context.Context, id int64, email string) error
+	// List includes deleted users.
+	List(ctx context.Context, q listing.Query) ([]*models.User, listing.Page, error)
+	// UpdateAccount saves the fields of the user that admins manage and
+	// records entry in the audit log, both or neither. A user deleted in
+	// the meantime returns models.ErrUserDeleted.
+	UpdateAccount(ctx context.Context, user *models.User, entry *models.AuditEntry) error
+}
+
+// UserTokenRepository stores the codes emailed to users. A user only ever has
+// one live code per purpose.
+type UserTokenRepository interface {
+	// Replace stores the new code in place of any the user already has for
+	// the purpose of token. The attempts made on the old code, and the
+	// lockout they led to, carry over to the new one so that asking for
+	// another code never buys more guesses; they only start over once a
+	// lockout has ended before now.
+	Replace(ctx context.Context, token *models.UserToken, now time.Time) error
+	// GetByUserID returns models.ErrTokenNotFound when the user has no code
+	// for the purpose.
+	GetByUserID(ctx context.Context, userId int64, purpose models.TokenPurpose) (*models.UserToken, error)
+	// RecordAttempt counts an attempt to use the code, as long as fewer than
+	// maxAttempts were made, and reports whether it was counted. The attempt
+	// that uses up the last one also locks the code until lockUntil. Counting
+	// before the code is checked keeps concurrent guesses within the limit.
+	RecordAttempt(ctx context.Context, id int64, maxAttempts int, lockUntil time.Time) (bool, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// Revoke marks the token revoked and reports false when it already was,
+	// so concurrent refreshes with the same token only succeed once.
+	Revoke(ctx context.Context, id int64, at time.Time) (bool, error)
+	RevokeByHash(ctx context.Context, hash string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userId int64, at time.Time) error
+}
+
+type PartnerRepository interface {
+	// Create returns models.ErrPartnerExists when the user already has a
+	// profile.
+	Create(ctx context.Context, partner *models.Partner) error
+	Update(ctx context.Context, partner *models.Partner) error
+	GetByID(ctx context.Context, id int64) (*models.Partner, error)
+	GetByUserID(ctx context.Context, userId int64) (*models.Partner, error)
+	List(ctx context.Context, q listing.Query) ([]*models.Partner, listing.Page, error)
+	// Review moves a pending profile to the given status. A profile that is
+	// no longer pending returns models.ErrPartnerAlreadyReview.
+	Review(ctx context.Context, partner *models.Partner, status models.PartnerStatus, reason string, at time.Time) error
+}
+
+// ProductRepository stores products together with their allergens and
+// dietary tags.
+type ProductRepository interface {
+	Create(ctx context.Context, product *models.Product) error
+	// Update saves the product details, but never who it belongs to.
+	Update(ctx context.Context, product *models.Product) error
+	// Delete returns models.ErrProductInUse when a magic bag lists the
+	// product.
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*models.Product, error)
+	// List returns the shared products and those of approved partners.
+	List(ctx context.Context, q listing.Query) ([]*models.Product, listing.Page, error)
+	// ListUsable returns the products the partner can put in their bags: the
+	// shared ones and their own.
+	ListUsable(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Product, listing.Page, error)
+	// Exist reports whether every one of the ids is a product the partner can
+	// use.
+	Exist(ctx context.Context, partnerId int64, ids []int64) (bool, error)
+}
+
+type MagicBagRepository interface {
+	// Create stores the bag together with its items.
+	Create(ctx context.Context, bag *models.MagicBag) error
+	// Update writes the fields changes sets, and replaces the items when it
+	// has them. A new quantity is written under the lock Reserve takes on the
+	// bag.
+	Update(ctx context.Context, bag *models.MagicBag, changes *models.MagicBagChanges) error
+	Withdraw(ctx context.Context, bag *models.MagicBag) error
+	// GetByID loads the bag without its items. Like the lists, it returns the
+	// bag labelled with the allergens and dietary tags of its items.
+	GetByID(ctx context.Context, id int64) (*models.MagicBag, error)
+	// Label sets the allergens and dietary tags of the bags from their stored
+	// items.
+	Label(ctx context.Context, bags ...*models.MagicBag) error
+	Items(ctx context.Context, bagId int64) ([]models.MagicBagItem, error)
+	// ListByPartner returns the bags without their items.
+	ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.MagicBag, listing.Page, error)
+	// ListAvailable returns the active, in stock bags of approved partners
+	// that can still be picked up at now, without their items. Partners whose
+	// user can no longer sell (see models.User.Sells) are left out.
+	ListAvailable(ctx context.Context, q listing.Query, now time.Time) ([]*models.MagicBag, listing.Page, error)
+}
+
+type TransactionRepository interface {
+	// Reserve records a pending purchase of one unit of the bag. It checks
+	// that the bag is on sale from an approved partner that can still sell,
+	// in stock and not past its pickup window, and that the user has not
+	// already bought from the partner on the partner's local day, then takes
+	// the unit out of stock. The checks and the insert
+	// are atomic with respect to other reservations.
+	Reserve(ctx context.Context, userId, bagId int64, paymentType models.PaymentType, now time.Time) (*models.Transaction, error)
+	// UpdatePayment saves the payment status, reference and pickup time of
+	// the transaction as long as its stored status is still from, returning
+	// models.ErrPaymentState otherwise. When releaseBag is set the unit goes
+	// back into stock in the same step.
+	UpdatePayment(ctx context.Context, transaction *models.Transaction, from payment.Status, releaseBag bool) error
+	GetByID(ctx context.Context, id int64) (*models.Transaction, error)
+	GetByReference(ctx context.Context, reference string) (*models.Transaction, error)
+	ListByUser(ctx context.Context, userId int64, q listing.Query) ([]*models.Transaction, listing.Page, error)
+}
+
+// FeedbackRepository keeps each partner's rating aggregate in step with the
+// feedback it stores.
+type FeedbackRepository interface {
+	// Create returns models.ErrFeedbackExists when the transaction already
+	// has feedback.
+	Create(ctx context.Context, feedback *models.Feedback) error
+	Update(ctx context.Context, feedback *models.Feedback) error
+	Delete(ctx context.Context, feedback *models.Feedback) error
+	GetByID(ctx context.Context, id int64) (*models.Feedback, error)
+	GetByTransaction(ctx context.Context, transactionId int64) (*models.Feedback, error)
+	ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Feedback, listing.Page, error)
+	// PartnerRating returns an empty aggregate for partners without any
+	// feedback.
+	PartnerRating(ctx context.Context, partnerId int64) (*models.PartnerRating, error)
+}
+
+// AuditLogRepository reads the audit log, which UserRepository.UpdateAccount
+// writes.
+type AuditLogRepository interface {
+	List(ctx context.Context, q listing.Query) ([]*models.AuditEntry, listing.Page, error)
+}
+
+// Repositories groups one implementation of every repository.
+type Repositories struct {
+	Users         UserRepository
+	UserTokens    UserTokenRepository
+	RefreshTokens RefreshTokenRepository
+	Partners      PartnerRepository
+	Products      ProductRepository
+	MagicBags     MagicBagRepository
+	Transactions  TransactionRepository
+	Feedback      FeedbackRepository
+	AuditLog      AuditLogRepository
+}
@@ -0,0 +1,325 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/memory"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/sqlstore"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testStore runs the same scenarios on every implementation of the
+// repositories, so that the in-memory store the tests use keeps behaving like
+// the databases.
+func testStore(t *testing.T, newRepos func(t *testing.T) repository.Repositories) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos(t)) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, newRepos(t)) })
+	t.Run("Partners", func(t *testing.T) { testPartners(t, newRepos(t)) })
+	t.Run("MagicBags", func(t *testing.T) { testMagicBags(t, newRepos(t)) })
+	t.Run("Reserve", func(t *testing.T) { testReserve(t, newRepos(t)) })
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, func(t *testing.T) repository.Repositories { return memory.New() })
+}
+
+func TestSQLite(t *testing.T) {
+	testStore(t, func(t *testing.T) repository.Repositories {
+		db, err := database.Open(config.DatabaseConfig{Driver: "sqlite", ConnectionString: filepath.Join(t.TempDir(), "test.db")})
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		err = database.Migrate(db)
+		if err != nil {
+			t.Fatalf("Migrate: %v", err)
+		}
+		return sqlstore.New(db)
+	})
+}
+
+func createUser(t *testing.T, repos repository.Repositories, email, phone string, userType models.UserType) *models.User {
+	t.Helper()
+
+	user := &models.User{FullName: "Jane Doe", Email: email, PasswordHash: "hash", PhoneNumber: phone, UserType: userType}
+	err := repos.Users.Create(context.Background(), user)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func createPartner(t *testing.T, repos repository.Repositories, email, phone string) *models.Partner {
+	t.Helper()
+	ctx := context.Background()
+
+	user := createUser(t, repos, email, phone, models.PARTNERS)
+	partner := &models.Partner{BRNumber: "BR-1", Address: "1 Market Street", Timezone: "UTC", Status: models.PartnerPending, UserID: user.Id}
+	err := repos.Partners.Create(ctx, partner)
+	if err != nil {
+		t.Fatalf("create partner: %v", err)
+	}
+	err = repos.Partners.Review(ctx, partner, models.PartnerApproved, "", time.Now())
+	if err != nil {
+		t.Fatalf("approve partner: %v", err)
+	}
+	return partner
+}
+
+func createBag(t *testing.T, repos repository.Repositories, partnerId int64, price float64, quantity int) *models.MagicBag {
+	t.Helper()
+
+	bag := &models.MagicBag{Title: "Bakery bag", BagPrice: price, Quantity: quantity, Status: models.MagicBagActive, PartnerID: partnerId}
+	err := repos.MagicBags.Create(context.Background(), bag)
+	if err != nil {
+		t.Fatalf("create bag: %v", err)
+	}
+	return bag
+}
+
+func testUsers(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, "jane@example.com", "+2348000000001", models.WASTEWARRIOR)
+	if user.Id == 0 || user.Status != models.UserInactive {
+		t.Fatalf("Create: got id %d and status %s, want an inactive user", user.Id, user.Status)
+	}
+
+	tests := []struct {
+		email string
+		phone string
+		want  error
+	}{
+		{"jane@example.com", "+2348000000002", models.ErrDuplicateEmail},
+		{"JANE@example.com", "+2348000000002", models.ErrDuplicateEmail},
+		{"john@example.com", "+2348000000001", models.ErrDuplicatePhone},
+	}
+	for _, tt := range tests {
+		err := repos.Users.Create(ctx, &models.User{FullName: "John Doe", Email: tt.email, PasswordHash: "hash", PhoneNumber: tt.phone, UserType: models.WASTEWARRIOR})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Create %s %s: got %v, want %v", tt.email, tt.phone, err, tt.want)
+		}
+	}
+
+	byEmail, err := repos.Users.GetByEmail(ctx, "jane@example.com")
+	if err != nil || byEmail.Id != user.Id || byEmail.PasswordHash != "hash" {
+		t.Fatalf("GetByEmail: got %+v, %v", byEmail, err)
+	}
+	_, err = repos.Users.GetByID(ctx, user.Id+100)
+	if !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("GetByID unknown: got %v, want ErrUserNotFound", err)
+	}
+
+	err = repos.Users.UpdateStatus(ctx, user.Id, models.UserActive)
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	createUser(t, repos, "john@example.com", "+2348000000002", models.WASTEWARRIOR)
+	err = repos.Users.UpdateEmail(ctx, user.Id, "john@example.com")
+	if !errors.Is(err, models.ErrDuplicateEmail) {
+		t.Fatalf("UpdateEmail to a taken email: got %v, want ErrDuplicateEmail", err)
+	}
+
+	v := validator.New()
+	q := listing.Parse(v, url.Values{"status": {models.UserActive}}, models.UserListing)
+	users, page, err := repos.Users.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 1 || users[0].Id != user.Id || page.Total != 1 {
+		t.Fatalf("List active users: got %d of %d, want jane only", len(users), page.Total)
+	}
+}
+
+func testTokens(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	now := time.Now()
+	user := createUser(t, repos, "jane@example.com", "+2348000000001", models.WASTEWARRIOR)
+
+	_, err := repos.UserTokens.GetByUserID(ctx, user.Id, models.PurposeVerifyEmail)
+	if !errors.Is(err, models.ErrTokenNotFound) {
+		t.Fatalf("GetByUserID without a code: got %v, want ErrTokenNotFound", err)
+	}
+
+	first := &models.UserToken{UserID: user.Id, Purpose: models.PurposeVerifyEmail, Email: user.Email, CodeHash: "first", ExpireAt: now.Add(time.Hour)}
+	err = repos.UserTokens.Replace(ctx, first, now)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	counted, err := repos.UserTokens.RecordAttempt(ctx, first.Id, 2, now.Add(time.Hour))
+	if err != nil || !counted {
+		t.Fatalf("RecordAttempt: got %t, %v", counted, err)
+	}
+
+	// The attempts carry over to the new code.
+	second := &models.UserToken{UserID: user.Id, Purpose: models.PurposeVerifyEmail, Email: user.Email, CodeHash: "second", ExpireAt: now.Add(time.Hour)}
+	err = repos.UserTokens.Replace(ctx, second, now)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	stored, err := repos.UserTokens.GetByUserID(ctx, user.Id, models.PurposeVerifyEmail)
+	if err != nil || stored.CodeHash != "second" || stored.Attempts != 1 {
+		t.Fatalf("GetByUserID: got %+v, %v, want the second code with one attempt", stored, err)
+	}
+	counted, err = repos.UserTokens.RecordAttempt(ctx, stored.Id, 2, now.Add(time.Hour))
+	if err != nil || !counted {
+		t.Fatalf("RecordAttempt: got %t, %v", counted, err)
+	}
+	counted, err = repos.UserTokens.RecordAttempt(ctx, stored.Id, 2, now.Add(time.Hour))
+	if err != nil || counted {
+		t.Fatalf("RecordAttempt past the limit: got %t, %v, want it refused", counted, err)
+	}
+
+	refresh := &models.RefreshToken{UserID: user.Id, TokenHash: "refresh", ExpireAt: now.Add(time.Hour)}
+	err = repos.RefreshTokens.Create(ctx, refresh)
+	if err != nil {
+		t.Fatalf("Create refresh token: %v", err)
+	}
+	revoked, err := repos.RefreshTokens.Revoke(ctx, refresh.Id, now)
+	if err != nil || !revoked {
+		t.Fatalf("Revoke: got %t, %v", revoked, err)
+	}
+	revoked, err = repos.RefreshTokens.Revoke(ctx, refresh.Id, now)
+	if err != nil || revoked {
+		t.Fatalf("Revoke twice: got %t, %v, want false", revoked, err)
+	}
+	_, err = repos.RefreshTokens.GetByHash(ctx, "unknown")
+	if !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("GetByHash unknown: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func testPartners(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	partner := createPartner(t, repos, "shop@example.com", "+2348000000001")
+
+	err := repos.Partners.Create(ctx, &models.Partner{BRNumber: "BR-2", Timezone: "UTC", Status: models.PartnerPending, UserID: partner.UserID})
+	if !errors.Is(err, models.ErrPartnerExists) {
+		t.Fatalf("Create a second profile: got %v, want ErrPartnerExists", err)
+	}
+	err = repos.Partners.Review(ctx, partner, models.PartnerRejected, "late", time.Now())
+	if !errors.Is(err, models.ErrPartnerAlreadyReview) {
+		t.Fatalf("Review twice: got %v, want ErrPartnerAlreadyReview", err)
+	}
+
+	stored, err := repos.Partners.GetByUserID(ctx, partner.UserID)
+	if err != nil || stored.ID != partner.ID || stored.Status != models.PartnerApproved || stored.ReviewedAt == nil {
+		t.Fatalf("GetByUserID: got %+v, %v", stored, err)
+	}
+	_, err = repos.Partners.GetByID(ctx, partner.ID+100)
+	if !errors.Is(err, models.ErrPartnerNotFound) {
+		t.Fatalf("GetByID unknown: got %v, want ErrPartnerNotFound", err)
+	}
+}
+
+func testMagicBags(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	now := time.Now()
+	partner := createPartner(t, repos, "shop@example.com", "+2348000000001")
+
+	cheap := createBag(t, repos, partner.ID, 2, 5)
+	dear := createBag(t, repos, partner.ID, 8, 5)
+	middle := createBag(t, repos, partner.ID, 5, 5)
+	createBag(t, repos, partner.ID, 1, 0)
+	withdrawn := createBag(t, repos, partner.ID, 3, 5)
+	err := repos.MagicBags.Withdraw(ctx, withdrawn)
+	if err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	v := validator.New()
+	q := listing.Parse(v, url.Values{"sort": {"-bag_price"}, "limit": {"2"}}, models.MagicBagListing)
+	if !v.Valid() {
+		t.Fatalf("Parse: %v", v.Errors)
+	}
+	bags, page, err := repos.MagicBags.ListAvailable(ctx, q, now)
+	if err != nil {
+		t.Fatalf("ListAvailable: %v", err)
+	}
+	if len(bags) != 2 || bags[0].ID != dear.ID || bags[1].ID != middle.ID || page.Total != 3 || page.NextCursor == "" {
+		t.Fatalf("ListAvailable: got %d bags of %d, want the dear and middle bags of 3 with a next page", len(bags), page.Total)
+	}
+
+	q = listing.Parse(v, url.Values{"sort": {"-bag_price"}, "limit": {"2"}, "cursor": {page.NextCursor}}, models.MagicBagListing)
+	if !v.Valid() {
+		t.Fatalf("Parse: %v", v.Errors)
+	}
+	bags, page, err = repos.MagicBags.ListAvailable(ctx, q, now)
+	if err != nil {
+		t.Fatalf("ListAvailable next page: %v", err)
+	}
+	if len(bags) != 1 || bags[0].ID != cheap.ID || page.NextCursor != "" {
+		t.Fatalf("ListAvailable next page: got %d bags, want only the cheap one", len(bags))
+	}
+
+	q = listing.Parse(v, url.Values{"bag_price[lt]": {"6"}}, models.MagicBagListing)
+	bags, page, err = repos.MagicBags.ListByPartner(ctx, partner.ID, q)
+	if err != nil {
+		t.Fatalf("ListByPartner: %v", err)
+	}
+	if page.Total != 4 {
+		t.Fatalf("ListByPartner under 6: got %d bags, want the sold out and withdrawn ones too", page.Total)
+	}
+
+	_, err = repos.MagicBags.GetByID(ctx, dear.ID+100)
+	if !errors.Is(err, models.ErrMagicBagNotFound) {
+		t.Fatalf("GetByID unknown: got %v, want ErrMagicBagNotFound", err)
+	}
+}
+
+func testReserve(t *testing.T, repos repository.Repositories) {
+	ctx := context.Background()
+	now := time.Now()
+	partner := createPartner(t, repos, "shop@example.com", "+2348000000001")
+	jane := createUser(t, repos, "jane@example.com", "+2348000000002", models.WASTEWARRIOR)
+	john := createUser(t, repos, "john@example.com", "+2348000000003", models.WASTEWARRIOR)
+	bag := createBag(t, repos, partner.ID, 4.5, 1)
+
+	transaction, err := repos.Transactions.Reserve(ctx, jane.Id, bag.ID, models.CARD, now)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if transaction.Amount != 4.5 || transaction.PaymentStatus != payment.StatusPending || transaction.PartnerID != partner.ID {
+		t.Fatalf("Reserve: got %+v", transaction)
+	}
+
+	_, err = repos.Transactions.Reserve(ctx, john.Id, bag.ID, models.CASH, now)
+	if !errors.Is(err, models.ErrMagicBagSoldOut) {
+		t.Fatalf("Reserve the last unit twice: got %v, want ErrMagicBagSoldOut", err)
+	}
+
+	other := createBag(t, repos, partner.ID, 3, 5)
+	_, err = repos.Transactions.Reserve(ctx, jane.Id, other.ID, models.CASH, now)
+	if !errors.Is(err, models.ErrDailyPurchaseLimit) {
+		t.Fatalf("Reserve from the same partner: got %v, want ErrDailyPurchaseLimit", err)
+	}
+
+	// A failed payment gives the unit back and no longer counts.
+	transaction.PaymentStatus = payment.StatusFailed
+	err = repos.Transactions.UpdatePayment(ctx, transaction, payment.StatusPending, true)
+	if err != nil {
+		t.Fatalf("UpdatePayment: %v", err)
+	}
+	err = repos.Transactions.UpdatePayment(ctx, transaction, payment.StatusPending, true)
+	if !errors.Is(err, models.ErrPaymentState) {
+		t.Fatalf("UpdatePayment from a stale status: got %v, want ErrPaymentState", err)
+	}
+	stored, err := repos.MagicBags.GetByID(ctx, bag.ID)
+	if err != nil || stored.Quantity != 1 {
+		t.Fatalf("GetByID: got %+v, %v, want the unit back in stock", stored, err)
+	}
+	_, err = repos.Transactions.Reserve(ctx, jane.Id, bag.ID, models.CASH, now)
+	if err != nil {
+		t.Fatalf("Reserve after a failed payment: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package sqlstore
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+)
+
+const auditColumns = "id, actor_id, target_user_id, action, old_values, new_values, reason, date_created"
+
+func scanAuditEntry(row scanner) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var oldValues, newValues string
+
+	err := row.Scan(&e.Id, &e.ActorID, &e.TargetUserID, &e.Action, &oldValues, &newValues, &e.Reason, &e.DateCreated)
+	if err != nil {
+		return nil, err
+	}
+	e.OldValues = json.RawMessage(oldValues)
+	e.NewValues = json.RawMessage(newValues)
+
+	return &e, nil
+}
+
+var auditTable = listTable[*models.AuditEntry]{
+	spec: models.AuditListing,
+	columns: map[string]string{
+		"id":             "id",
+		"actor_id":       "actor_id",
+		"target_user_id": "target_user_id",
+		"action":         "action",
+		"date_created":   "date_created",
+	},
+	selects: auditColumns,
+	scan:    scanAuditEntry,
+}
+
+type AuditLogRepository struct {
+	db *database.DB
+}
+
+func (r *AuditLogRepository) List(ctx context.Context, q listing.Query) ([]*models.AuditEntry, listing.Page, error) {
+	return auditTable.list(ctx, r.db, q, "audit_log", "")
+}
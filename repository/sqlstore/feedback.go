@@ -0,0 +1,213 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+const feedbackColumns = "id, comment, rating, date_created, date_updated, transaction_id, user_id, partner_id"
+
+func scanFeedback(row scanner) (*models.Feedback, error) {
+	var f models.Feedback
+	var comment sql.NullString
+
+	err := row.Scan(&f.Id, &comment, &f.Rating, &f.DateCreated, &f.DateUpdated, &f.TransactionID, &f.UserID, &f.PartnerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrFeedbackNotFound
+		}
+		return nil, err
+	}
+	f.Comment = comment.String
+
+	return &f, nil
+}
+
+var feedbackTable = listTable[*models.Feedback]{
+	spec: models.FeedbackListing,
+	columns: map[string]string{
+		"id":           "id",
+		"rating":       "rating",
+		"date_created": "date_created",
+	},
+	selects: feedbackColumns,
+	scan:    scanFeedback,
+}
+
+type FeedbackRepository struct {
+	db *database.DB
+}
+
+func (r *FeedbackRepository) Create(ctx context.Context, feedback *models.Feedback) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO feedback (rating, comment, transaction_id, user_id, partner_id)
+	VALUES (?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(ctx, query, feedback.Rating, feedback.Comment, feedback.TransactionID, feedback.UserID, feedback.PartnerID)
+	if err != nil {
+		if r.db.Dialect.IsUniqueViolation(err) {
+			return models.ErrFeedbackExists
+		}
+		return err
+	}
+	feedback.Id = id
+
+	err = adjustPartnerRating(ctx, tx, feedback.PartnerID, feedback.Rating, 1)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	feedback.DateCreated = time.Now()
+	feedback.DateUpdated = feedback.DateCreated
+	return nil
+}
+
+// Update saves the new rating and comment, moving the stored rating out of
+// the partner aggregate and the new one in.
+func (r *FeedbackRepository) Update(ctx context.Context, feedback *models.Feedback) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := lockFeedbackRating(ctx, tx, feedback.Id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE feedback SET rating = ?, comment = ? WHERE id = ?", feedback.Rating, feedback.Comment, feedback.Id)
+	if err != nil {
+		return err
+	}
+
+	if previous != feedback.Rating {
+		err = adjustPartnerRating(ctx, tx, feedback.PartnerID, previous, -1)
+		if err != nil {
+			return err
+		}
+		err = adjustPartnerRating(ctx, tx, feedback.PartnerID, feedback.Rating, 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *FeedbackRepository) Delete(ctx context.Context, feedback *models.Feedback) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rating, err := lockFeedbackRating(ctx, tx, feedback.Id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM feedback WHERE id = ?", feedback.Id)
+	if err != nil {
+		return err
+	}
+
+	err = adjustPartnerRating(ctx, tx, feedback.PartnerID, rating, -1)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *FeedbackRepository) GetByID(ctx context.Context, id int64) (*models.Feedback, error) {
+	query := fmt.Sprintf("SELECT %s FROM feedback WHERE id = ?", feedbackColumns)
+	return scanFeedback(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *FeedbackRepository) GetByTransaction(ctx context.Context, transactionId int64) (*models.Feedback, error) {
+	query := fmt.Sprintf("SELECT %s FROM feedback WHERE transaction_id = ?", feedbackColumns)
+	return scanFeedback(r.db.QueryRowContext(ctx, query, transactionId))
+}
+
+func (r *FeedbackRepository) ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Feedback, listing.Page, error) {
+	return feedbackTable.list(ctx, r.db, q, "feedback", "partner_id = ?", partnerId)
+}
+
+func (r *FeedbackRepository) PartnerRating(ctx context.Context, partnerId int64) (*models.PartnerRating, error) {
+	query := `
+	SELECT rating_count, rating_sum, rating_1, rating_2, rating_3, rating_4, rating_5
+	FROM partner_ratings WHERE partner_id = ?
+	`
+	var count, sum int
+	counts := make([]int, 5)
+	err := r.db.QueryRowContext(ctx, query, partnerId).Scan(&count, &sum, &counts[0], &counts[1], &counts[2], &counts[3], &counts[4])
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	rating := &models.PartnerRating{
+		PartnerID:    partnerId,
+		Count:        count,
+		Distribution: make(map[int]int, 5),
+	}
+	for i, n := range counts {
+		rating.Distribution[i+1] = n
+	}
+	if count > 0 {
+		rating.Average = float64(sum) / float64(count)
+	}
+
+	return rating, nil
+}
+
+func lockFeedbackRating(ctx context.Context, tx *database.Tx, id int64) (int, error) {
+	var rating int
+	query := "SELECT rating FROM feedback WHERE id = ? " + tx.Dialect.ForUpdate(false)
+	err := tx.QueryRowContext(ctx, query, id).Scan(&rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrFeedbackNotFound
+		}
+		return 0, err
+	}
+	return rating, nil
+}
+
+// adjustPartnerRating adds (delta 1) or removes (delta -1) a single rating
+// from the partner aggregate.
+func adjustPartnerRating(ctx context.Context, tx *database.Tx, partnerId int64, rating, delta int) error {
+	if rating < 1 || rating > 5 {
+		return fmt.Errorf("rating %d out of range", rating)
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO partner_ratings (partner_id) VALUES (?) "+tx.Dialect.IgnoreConflict("partner_id"), partnerId)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+	UPDATE partner_ratings
+	SET rating_count = rating_count + ?, rating_sum = rating_sum + ?, rating_%d = rating_%d + ?
+	WHERE partner_id = ?
+	`, rating, rating)
+	_, err = tx.ExecContext(ctx, query, delta, delta*rating, delta, partnerId)
+	return err
+}
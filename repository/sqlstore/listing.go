@@ -0,0 +1,184 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"strings"
+)
+
+var operatorSQL = map[listing.Operator]string{
+	listing.Eq:  "=",
+	listing.Ne:  "<>",
+	listing.Lt:  "<",
+	listing.Lte: "<=",
+	listing.Gt:  ">",
+	listing.Gte: ">=",
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, with the escape
+// character named in the query since the databases default to different ones.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// listTable says how the rows of one list are read.
+type listTable[T any] struct {
+	spec listing.Spec[T]
+	// columns maps every field of spec to its column, except the custom ones.
+	columns map[string]string
+	// custom builds the conditions of filters on fields that are not a
+	// column, such as sets kept in another table.
+	custom map[string]func(listing.Filter) (string, []any)
+	// selects is the column list scan reads.
+	selects string
+	scan    func(scanner) (T, error)
+}
+
+// list returns the page of rows q asks for among those of from that match
+// where, which may be empty. It reads one row more than the limit to learn
+// whether another page follows.
+func (t listTable[T]) list(ctx context.Context, db *database.DB, q listing.Query, from, where string, args ...any) ([]T, listing.Page, error) {
+	var page listing.Page
+
+	var conditions []string
+	if where != "" {
+		conditions = append(conditions, where)
+	}
+	filters, filterArgs := t.filters(db.Dialect, q.Filters)
+	conditions = append(conditions, filters...)
+	args = append(append([]any{}, args...), filterArgs...)
+
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from+whereClause(conditions), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, page, err
+	}
+
+	if q.After != nil {
+		after, afterArgs := t.after(db.Dialect, q)
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT ? OFFSET ?", t.selects, from, whereClause(conditions), t.order(db.Dialect, q.Sort))
+	args = append(args, q.Limit+1, q.Offset)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, page, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := t.scan(rows)
+		if err != nil {
+			return nil, page, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, page, err
+	}
+
+	if len(items) > q.Limit {
+		items = items[:q.Limit]
+		page.NextCursor = t.spec.Cursor(q, items[len(items)-1])
+	}
+	return items, page, nil
+}
+
+// column returns the column of a field, made comparable by time when it is
+// one, together with the placeholder to compare it with.
+func (t listTable[T]) column(dialect database.Dialect, field string) (string, string) {
+	if t.spec.Fields[field].Kind == listing.Time {
+		return dialect.CompareTime(t.columns[field]), dialect.CompareTime("?")
+	}
+	return t.columns[field], "?"
+}
+
+func (t listTable[T]) filters(dialect database.Dialect, filters []listing.Filter) ([]string, []any) {
+	var conditions []string
+	var args []any
+
+	for _, f := range filters {
+		if build, ok := t.custom[f.Field]; ok {
+			condition, conditionArgs := build(f)
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+			continue
+		}
+
+		column, placeholder := t.column(dialect, f.Field)
+		switch f.Operator {
+		case listing.In:
+			list := strings.TrimSuffix(strings.Repeat(placeholder+", ", len(f.Values)), ", ")
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, list))
+			args = append(args, f.Values...)
+		case listing.Contains:
+			// Lowering both sides makes the match ignore case on PostgreSQL
+			// too, whose LIKE does not.
+			conditions = append(conditions, fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", column))
+			args = append(args, "%"+likeEscaper.Replace(strings.ToLower(f.Values[0].(string)))+"%")
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", column, operatorSQL[f.Operator], placeholder))
+			args = append(args, f.Values...)
+		}
+	}
+
+	return conditions, args
+}
+
+// after keeps the rows that come after the cursor in the order of q:
+// (a > ?) OR (a = ? AND b > ?) OR ...
+func (t listTable[T]) after(dialect database.Dialect, q listing.Query) (string, []any) {
+	var ors []string
+	var args []any
+
+	for i, s := range q.Sort {
+		var ands []string
+		for j := 0; j < i; j++ {
+			column, placeholder := t.column(dialect, q.Sort[j].Field)
+			ands = append(ands, fmt.Sprintf("%s = %s", column, placeholder))
+			args = append(args, q.After[j])
+		}
+
+		op := ">"
+		if s.Desc {
+			op = "<"
+		}
+		column, placeholder := t.column(dialect, s.Field)
+		ands = append(ands, fmt.Sprintf("%s %s %s", column, op, placeholder))
+		args = append(args, q.After[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func (t listTable[T]) order(dialect database.Dialect, sorts []listing.Sort) string {
+	terms := make([]string, len(sorts))
+	for i, s := range sorts {
+		column, _ := t.column(dialect, s.Field)
+		terms[i] = column + " ASC"
+		if s.Desc {
+			terms[i] = column + " DESC"
+		}
+	}
+	return strings.Join(terms, ", ")
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// qualify prefixes every column of a column list with a table alias.
+func qualify(alias, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = alias + "." + name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,295 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"strings"
+	"time"
+)
+
+const magicBagColumns = "id, title, description, bag_price, quantity, weight_kg, pickup_start, pickup_end, status, date_created, date_updated, partner_id"
+
+func scanMagicBag(row scanner) (*models.MagicBag, error) {
+	var bag models.MagicBag
+	var description sql.NullString
+	var pickupStart, pickupEnd sql.NullTime
+
+	err := row.Scan(&bag.ID, &bag.Title, &description, &bag.BagPrice, &bag.Quantity, &bag.WeightKg, &pickupStart, &pickupEnd, &bag.Status, &bag.DateCreated, &bag.DateUpdated, &bag.PartnerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrMagicBagNotFound
+		}
+		return nil, err
+	}
+
+	bag.Description = description.String
+	if pickupStart.Valid {
+		bag.PickupStart = &pickupStart.Time
+	}
+	if pickupEnd.Valid {
+		bag.PickupEnd = &pickupEnd.Time
+	}
+
+	return &bag, nil
+}
+
+var magicBagTable = listTable[*models.MagicBag]{
+	spec: models.MagicBagListing,
+	columns: map[string]string{
+		"id":           "mb.id",
+		"partner_id":   "mb.partner_id",
+		"status":       "mb.status",
+		"bag_price":    "mb.bag_price",
+		"quantity":     "mb.quantity",
+		"weight_kg":    "mb.weight_kg",
+		"pickup_start": "mb.pickup_start",
+		"pickup_end":   "mb.pickup_end",
+		"date_created": "mb.date_created",
+	},
+	custom: map[string]func(listing.Filter) (string, []any){
+		"allergens":    allergenLabel.bagFilter("mb.id"),
+		"dietary_tags": dietaryTagLabel.bagFilter("mb.id"),
+	},
+	selects: qualify("mb", magicBagColumns),
+	scan:    scanMagicBag,
+}
+
+type MagicBagRepository struct {
+	db *database.DB
+}
+
+func (r *MagicBagRepository) Create(ctx context.Context, bag *models.MagicBag) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO magic_bags (title, description, bag_price, quantity, weight_kg, pickup_start, pickup_end, status, partner_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(ctx, query, bag.Title, bag.Description, bag.BagPrice, bag.Quantity, bag.WeightKg, bag.PickupStart, bag.PickupEnd, bag.Status, bag.PartnerID)
+	if err != nil {
+		return err
+	}
+	bag.ID = id
+
+	err = replaceMagicBagItems(ctx, tx, bag)
+	if err != nil {
+		return err
+	}
+
+	err = loadTimestamps(ctx, tx, "magic_bags", id, &bag.DateCreated, &bag.DateUpdated)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *MagicBagRepository) Update(ctx context.Context, bag *models.MagicBag, changes *models.MagicBagChanges) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sets []string
+	var args []any
+	set := func(column string, value any) {
+		sets = append(sets, column+" = ?")
+		args = append(args, value)
+	}
+	if changes.Title != nil {
+		set("title", *changes.Title)
+	}
+	if changes.Description != nil {
+		set("description", *changes.Description)
+	}
+	if changes.BagPrice != nil {
+		set("bag_price", *changes.BagPrice)
+	}
+	if changes.WeightKg != nil {
+		set("weight_kg", *changes.WeightKg)
+	}
+	if changes.PickupStart != nil {
+		set("pickup_start", *changes.PickupStart)
+	}
+	if changes.PickupEnd != nil {
+		set("pickup_end", *changes.PickupEnd)
+	}
+	if changes.Quantity != nil {
+		// Take the lock Reserve takes, so the new stock is not written
+		// between a purchase reading the bag and taking its unit.
+		var lockedId int64
+		err = tx.QueryRowContext(ctx, "SELECT id FROM magic_bags WHERE id = ? "+r.db.Dialect.ForUpdate(false), bag.ID).Scan(&lockedId)
+		if err != nil {
+			return err
+		}
+		set("quantity", *changes.Quantity)
+	}
+
+	if len(sets) > 0 {
+		query := fmt.Sprintf("UPDATE magic_bags SET %s WHERE id = ? AND partner_id = ?", strings.Join(sets, ", "))
+		_, err = tx.ExecContext(ctx, query, append(args, bag.ID, bag.PartnerID)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if changes.Items != nil {
+		err = replaceMagicBagItems(ctx, tx, bag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MagicBagRepository) Withdraw(ctx context.Context, bag *models.MagicBag) error {
+	query := "UPDATE magic_bags SET status = ? WHERE id = ? AND partner_id = ?"
+	_, err := r.db.ExecContext(ctx, query, models.MagicBagWithdrawn, bag.ID, bag.PartnerID)
+	if err != nil {
+		return err
+	}
+	bag.Status = models.MagicBagWithdrawn
+	return nil
+}
+
+func (r *MagicBagRepository) GetByID(ctx context.Context, id int64) (*models.MagicBag, error) {
+	query := fmt.Sprintf("SELECT %s FROM magic_bags WHERE id = ?", magicBagColumns)
+	bag, err := scanMagicBag(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Label(ctx, bag)
+	if err != nil {
+		return nil, err
+	}
+
+	return bag, nil
+}
+
+func (r *MagicBagRepository) Label(ctx context.Context, bags ...*models.MagicBag) error {
+	if len(bags) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(bags))
+	for i, bag := range bags {
+		ids[i] = bag.ID
+	}
+
+	query := fmt.Sprintf("SELECT magic_bag_id, product_id FROM magic_bag_products WHERE magic_bag_id IN (%s)", placeholders(len(ids)))
+	rows, err := r.db.QueryContext(ctx, query, int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	contents := make(map[int64][]int64)
+	var productIds []int64
+	for rows.Next() {
+		var bagId, productId int64
+		err := rows.Scan(&bagId, &productId)
+		if err != nil {
+			return err
+		}
+		contents[bagId] = append(contents[bagId], productId)
+		productIds = append(productIds, productId)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// SQLite has a single connection, which the rows hold until closed.
+	rows.Close()
+
+	labels, err := productLabels(ctx, r.db, productIds)
+	if err != nil {
+		return err
+	}
+
+	for _, bag := range bags {
+		products := make([]*models.Product, len(contents[bag.ID]))
+		for i, productId := range contents[bag.ID] {
+			products[i] = labels[productId]
+		}
+		bag.Label(products)
+	}
+	return nil
+}
+
+func (r *MagicBagRepository) Items(ctx context.Context, bagId int64) ([]models.MagicBagItem, error) {
+	query := "SELECT id, quantity, magic_bag_id, product_id FROM magic_bag_products WHERE magic_bag_id = ? ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, query, bagId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.MagicBagItem{}
+	for rows.Next() {
+		var item models.MagicBagItem
+		err := rows.Scan(&item.ID, &item.Quantity, &item.MagicBagID, &item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *MagicBagRepository) ListByPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.MagicBag, listing.Page, error) {
+	return r.list(ctx, q, "magic_bags mb", "mb.partner_id = ?", partnerId)
+}
+
+func (r *MagicBagRepository) ListAvailable(ctx context.Context, q listing.Query, now time.Time) ([]*models.MagicBag, listing.Page, error) {
+	from := "magic_bags mb JOIN partners p ON p.id = mb.partner_id JOIN users u ON u.id = p.user_id"
+	where := fmt.Sprintf("mb.status = ? AND mb.quantity > 0 AND p.status = ? AND (mb.pickup_end IS NULL OR %s > %s)"+
+		" AND u.user_type = ? AND u.suspended_at IS NULL AND u.deleted_at IS NULL",
+		r.db.Dialect.CompareTime("mb.pickup_end"), r.db.Dialect.CompareTime("?"))
+	return r.list(ctx, q, from, where, models.MagicBagActive, models.PartnerApproved, now, models.PARTNERS)
+}
+
+func (r *MagicBagRepository) list(ctx context.Context, q listing.Query, from, where string, args ...any) ([]*models.MagicBag, listing.Page, error) {
+	bags, page, err := magicBagTable.list(ctx, r.db, q, from, where, args...)
+	if err != nil {
+		return nil, page, err
+	}
+
+	err = r.Label(ctx, bags...)
+	if err != nil {
+		return nil, page, err
+	}
+
+	return bags, page, nil
+}
+
+func replaceMagicBagItems(ctx context.Context, tx *database.Tx, bag *models.MagicBag) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM magic_bag_products WHERE magic_bag_id = ?", bag.ID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO magic_bag_products (quantity, magic_bag_id, product_id) VALUES (?, ?, ?)"
+	for i := range bag.Items {
+		item := &bag.Items[i]
+		item.MagicBagID = bag.ID
+		id, err := tx.Insert(ctx, query, item.Quantity, item.MagicBagID, item.ProductID)
+		if err != nil {
+			return err
+		}
+		item.ID = id
+	}
+
+	return nil
+}
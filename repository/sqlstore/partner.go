@@ -0,0 +1,112 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+const partnerColumns = "id, business_number, logo, address, timezone, status, rejection_reason, reviewed_at, date_created, date_updated, user_id"
+
+func scanPartner(row scanner) (*models.Partner, error) {
+	var p models.Partner
+	var logo, address, reason sql.NullString
+	var reviewedAt sql.NullTime
+
+	err := row.Scan(&p.ID, &p.BRNumber, &logo, &address, &p.Timezone, &p.Status, &reason, &reviewedAt, &p.DateCreated, &p.DateUpdated, &p.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrPartnerNotFound
+		}
+		return nil, err
+	}
+
+	p.Logo = logo.String
+	p.Address = address.String
+	p.RejectionReason = reason.String
+	if reviewedAt.Valid {
+		p.ReviewedAt = &reviewedAt.Time
+	}
+
+	return &p, nil
+}
+
+var partnerTable = listTable[*models.Partner]{
+	spec: models.PartnerListing,
+	columns: map[string]string{
+		"id":           "id",
+		"status":       "status",
+		"reviewed_at":  "reviewed_at",
+		"date_created": "date_created",
+	},
+	selects: partnerColumns,
+	scan:    scanPartner,
+}
+
+type PartnerRepository struct {
+	db *database.DB
+}
+
+func (r *PartnerRepository) Create(ctx context.Context, partner *models.Partner) error {
+	query := `
+	INSERT INTO partners (business_number, user_id, logo, address, timezone, status)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	id, err := r.db.Insert(ctx, query, partner.BRNumber, partner.UserID, partner.Logo, partner.Address, partner.Timezone, partner.Status)
+	if err != nil {
+		if r.db.Dialect.IsUniqueViolation(err) {
+			return models.ErrPartnerExists
+		}
+		return err
+	}
+	partner.ID = id
+	return loadTimestamps(ctx, r.db, "partners", id, &partner.DateCreated, &partner.DateUpdated)
+}
+
+func (r *PartnerRepository) Update(ctx context.Context, partner *models.Partner) error {
+	query := `
+	UPDATE partners SET business_number = ?, logo = ?, address = ?, timezone = ?, status = ?, rejection_reason = ?, reviewed_at = ?
+	WHERE id = ?
+	`
+	reason := sql.NullString{String: partner.RejectionReason, Valid: partner.RejectionReason != ""}
+	_, err := r.db.ExecContext(ctx, query, partner.BRNumber, partner.Logo, partner.Address, partner.Timezone, partner.Status, reason, partner.ReviewedAt, partner.ID)
+	return err
+}
+
+func (r *PartnerRepository) GetByID(ctx context.Context, id int64) (*models.Partner, error) {
+	query := fmt.Sprintf("SELECT %s FROM partners WHERE id = ?", partnerColumns)
+	return scanPartner(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *PartnerRepository) GetByUserID(ctx context.Context, userId int64) (*models.Partner, error) {
+	query := fmt.Sprintf("SELECT %s FROM partners WHERE user_id = ?", partnerColumns)
+	return scanPartner(r.db.QueryRowContext(ctx, query, userId))
+}
+
+func (r *PartnerRepository) List(ctx context.Context, q listing.Query) ([]*models.Partner, listing.Page, error) {
+	return partnerTable.list(ctx, r.db, q, "partners", "")
+}
+
+func (r *PartnerRepository) Review(ctx context.Context, partner *models.Partner, status models.PartnerStatus, reason string, at time.Time) error {
+	query := `
+	UPDATE partners SET status = ?, rejection_reason = ?, reviewed_at = ?
+	WHERE id = ? AND status = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, status, sql.NullString{String: reason, Valid: reason != ""}, at, partner.ID, models.PartnerPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrPartnerAlreadyReview
+	}
+
+	partner.Status = status
+	partner.RejectionReason = reason
+	partner.ReviewedAt = &at
+	return nil
+}
@@ -0,0 +1,342 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+)
+
+const productColumns = "id, name, category, unit_weight_kg, partner_id, date_created, date_updated"
+
+func scanProduct(row scanner) (*models.Product, error) {
+	var product models.Product
+	var partnerId sql.NullInt64
+
+	err := row.Scan(&product.Id, &product.Name, &product.Category, &product.UnitWeightKg, &partnerId, &product.DateCreated, &product.DateUpdated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	if partnerId.Valid {
+		product.PartnerID = &partnerId.Int64
+	}
+
+	return &product, nil
+}
+
+// productLabel is a table holding one allergen or dietary tag of a product
+// per row.
+type productLabel struct {
+	table  string
+	column string
+}
+
+var (
+	allergenLabel   = productLabel{table: "product_allergens", column: "allergen"}
+	dietaryTagLabel = productLabel{table: "product_dietary_tags", column: "tag"}
+)
+
+func (l productLabel) replace(ctx context.Context, tx *database.Tx, productId int64, values []string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE product_id = ?", l.table), productId)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (product_id, %s) VALUES (?, ?)", l.table, l.column)
+	for _, value := range values {
+		_, err := tx.ExecContext(ctx, query, productId, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load calls add with every label of the products with the ids.
+func (l productLabel) load(ctx context.Context, db *database.DB, ids []int64, add func(productId int64, value string)) error {
+	query := fmt.Sprintf("SELECT product_id, %s FROM %s WHERE product_id IN (%s)", l.column, l.table, placeholders(len(ids)))
+	rows, err := db.QueryContext(ctx, query, int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productId int64
+		var value string
+		err := rows.Scan(&productId, &value)
+		if err != nil {
+			return err
+		}
+		add(productId, value)
+	}
+
+	return rows.Err()
+}
+
+// productFilter builds the condition of an All or None filter on the labels
+// of the product whose id is in the column.
+func (l productLabel) productFilter(column string) func(listing.Filter) (string, []any) {
+	return func(f listing.Filter) (string, []any) {
+		want := 0
+		if f.Operator == listing.All {
+			want = len(f.Values)
+		}
+		query := fmt.Sprintf("(SELECT COUNT(*) FROM %s l WHERE l.product_id = %s AND l.%s IN (%s)) = %d", l.table, column, l.column, placeholders(len(f.Values)), want)
+		return query, f.Values
+	}
+}
+
+// bagFilter builds the condition of an All or None filter on the labels of
+// the bag whose id is in the column: it must list products, and every one of
+// them must match.
+func (l productLabel) bagFilter(column string) func(listing.Filter) (string, []any) {
+	return func(f listing.Filter) (string, []any) {
+		product, args := l.productFilter("i.product_id")(f)
+		query := fmt.Sprintf("(EXISTS (SELECT 1 FROM magic_bag_products i WHERE i.magic_bag_id = %s) AND NOT EXISTS (SELECT 1 FROM magic_bag_products i WHERE i.magic_bag_id = %s AND NOT (%s)))", column, column, product)
+		return query, args
+	}
+}
+
+// productLabels loads the allergens and dietary tags of the products with
+// the ids, returned as products holding nothing else.
+func productLabels(ctx context.Context, db *database.DB, ids []int64) (map[int64]*models.Product, error) {
+	products := make(map[int64]*models.Product, len(ids))
+	for _, id := range ids {
+		products[id] = &models.Product{Id: id, Allergens: []models.Allergen{}, DietaryTags: []models.DietaryTag{}}
+	}
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	err := allergenLabel.load(ctx, db, ids, func(id int64, value string) {
+		products[id].Allergens = append(products[id].Allergens, models.Allergen(value))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = dietaryTagLabel.load(ctx, db, ids, func(id int64, value string) {
+		products[id].DietaryTags = append(products[id].DietaryTags, models.DietaryTag(value))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		product.SortLabels()
+	}
+	return products, nil
+}
+
+var productTable = listTable[*models.Product]{
+	spec: models.ProductListing,
+	columns: map[string]string{
+		"id":             "p.id",
+		"name":           "p.name",
+		"category":       "p.category",
+		"unit_weight_kg": "p.unit_weight_kg",
+		"partner_id":     "p.partner_id",
+		"shared":         "(p.partner_id IS NULL)",
+		"date_created":   "p.date_created",
+	},
+	custom: map[string]func(listing.Filter) (string, []any){
+		"allergens":    allergenLabel.productFilter("p.id"),
+		"dietary_tags": dietaryTagLabel.productFilter("p.id"),
+	},
+	selects: qualify("p", productColumns),
+	scan:    scanProduct,
+}
+
+type ProductRepository struct {
+	db *database.DB
+}
+
+func (r *ProductRepository) Create(ctx context.Context, product *models.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO products (name, category, unit_weight_kg, partner_id) VALUES (?, ?, ?, ?)"
+	id, err := tx.Insert(ctx, query, product.Name, product.Category, product.UnitWeightKg, product.PartnerID)
+	if err != nil {
+		return err
+	}
+	product.Id = id
+
+	err = saveProductLabels(ctx, tx, product)
+	if err != nil {
+		return err
+	}
+
+	err = loadTimestamps(ctx, tx, "products", id, &product.DateCreated, &product.DateUpdated)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ProductRepository) Update(ctx context.Context, product *models.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE products SET name = ?, category = ?, unit_weight_kg = ? WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, product.Name, product.Category, product.UnitWeightKg, product.Id)
+	if err != nil {
+		return err
+	}
+
+	err = saveProductLabels(ctx, tx, product)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Bag items reference the product row, so locking it keeps bags from
+	// listing the product between the check and the delete.
+	var lockedId int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ? "+tx.Dialect.ForUpdate(false), id).Scan(&lockedId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrProductNotFound
+		}
+		return err
+	}
+
+	var bags int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM magic_bag_products WHERE product_id = ?", id).Scan(&bags)
+	if err != nil {
+		return err
+	}
+	if bags > 0 {
+		return models.ErrProductInUse
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ProductRepository) GetByID(ctx context.Context, id int64) (*models.Product, error) {
+	query := fmt.Sprintf("SELECT %s FROM products WHERE id = ?", productColumns)
+	product, err := scanProduct(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.label(ctx, []*models.Product{product})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (r *ProductRepository) List(ctx context.Context, q listing.Query) ([]*models.Product, listing.Page, error) {
+	from := "products p LEFT JOIN partners pt ON pt.id = p.partner_id"
+	where := "(p.partner_id IS NULL OR pt.status = ?)"
+	return r.list(ctx, q, from, where, models.PartnerApproved)
+}
+
+func (r *ProductRepository) ListUsable(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Product, listing.Page, error) {
+	return r.list(ctx, q, "products p", "(p.partner_id IS NULL OR p.partner_id = ?)", partnerId)
+}
+
+func (r *ProductRepository) Exist(ctx context.Context, partnerId int64, ids []int64) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM products WHERE id IN (%s) AND (partner_id IS NULL OR partner_id = ?)", placeholders(len(ids)))
+	var count int
+	err := r.db.QueryRowContext(ctx, query, append(int64Args(ids), partnerId)...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == len(ids), nil
+}
+
+func (r *ProductRepository) list(ctx context.Context, q listing.Query, from, where string, args ...any) ([]*models.Product, listing.Page, error) {
+	products, page, err := productTable.list(ctx, r.db, q, from, where, args...)
+	if err != nil {
+		return nil, page, err
+	}
+
+	err = r.label(ctx, products)
+	if err != nil {
+		return nil, page, err
+	}
+
+	return products, page, nil
+}
+
+// label loads the allergens and dietary tags of the products.
+func (r *ProductRepository) label(ctx context.Context, products []*models.Product) error {
+	ids := make([]int64, len(products))
+	for i, product := range products {
+		ids[i] = product.Id
+	}
+
+	labels, err := productLabels(ctx, r.db, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.Allergens = labels[product.Id].Allergens
+		product.DietaryTags = labels[product.Id].DietaryTags
+	}
+	return nil
+}
+
+func saveProductLabels(ctx context.Context, tx *database.Tx, product *models.Product) error {
+	allergens := make([]string, len(product.Allergens))
+	for i, allergen := range product.Allergens {
+		allergens[i] = string(allergen)
+	}
+	err := allergenLabel.replace(ctx, tx, product.Id, allergens)
+	if err != nil {
+		return err
+	}
+
+	tags := make([]string, len(product.DietaryTags))
+	for i, tag := range product.DietaryTags {
+		tags[i] = string(tag)
+	}
+	return dietaryTagLabel.replace(ctx, tx, product.Id, tags)
+}
+
+func int64Args(ids []int64) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
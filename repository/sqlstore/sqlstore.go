@@ -0,0 +1,51 @@
+// Package sqlstore implements the repositories on top of database/sql and
+// the schema in database/migrations. Queries stick to SQL that every
+// supported database accepts and ask the database.Dialect for the rest.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"strings"
+	"time"
+)
+
+// New returns every repository backed by db.
+func New(db *database.DB) repository.Repositories {
+	return repository.Repositories{
+		Users:         &UserRepository{db: db},
+		UserTokens:    &UserTokenRepository{db: db},
+		RefreshTokens: &RefreshTokenRepository{db: db},
+		Partners:      &PartnerRepository{db: db},
+		Products:      &ProductRepository{db: db},
+		MagicBags:     &MagicBagRepository{db: db},
+		Transactions:  &TransactionRepository{db: db},
+		Feedback:      &FeedbackRepository{db: db},
+		AuditLog:      &AuditLogRepository{db: db},
+	}
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// rowQuerier is satisfied by both *database.DB and *database.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadTimestamps reads back the date_created and date_updated the database
+// gave the new row of the table, so that a create can answer with them.
+func loadTimestamps(ctx context.Context, q rowQuerier, table string, id int64, created, updated *time.Time) error {
+	query := fmt.Sprintf("SELECT date_created, date_updated FROM %s WHERE id = ?", table)
+	return q.QueryRowContext(ctx, query, id).Scan(created, updated)
+}
+
+// placeholders returns "?, ?, ..." for n query arguments.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,102 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestRepos returns the repositories on a new, migrated SQLite database.
+func newTestRepos(t *testing.T) repository.Repositories {
+	t.Helper()
+
+	db, err := database.Open(config.DatabaseConfig{Driver: "sqlite", ConnectionString: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = database.Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return New(db)
+}
+
+func checkTimestamps(t *testing.T, what string, created, updated, storedCreated, storedUpdated time.Time) {
+	t.Helper()
+
+	if created.IsZero() || updated.IsZero() {
+		t.Errorf("%s: got date_created %v and date_updated %v, want them set", what, created, updated)
+	}
+	if !created.Equal(storedCreated) || !updated.Equal(storedUpdated) {
+		t.Errorf("%s: got %v and %v, want the stored %v and %v", what, created, updated, storedCreated, storedUpdated)
+	}
+}
+
+func TestCreateSetsTimestamps(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+
+	user := &models.User{FullName: "Jane Doe", Email: "jane@example.com", PhoneNumber: "+2348000000001", UserType: models.PARTNERS}
+	token := &models.UserToken{Purpose: models.PurposeVerifyEmail, Email: user.Email, CodeHash: "hash", ExpireAt: time.Now().Add(time.Hour)}
+	err := repos.Users.Register(ctx, user, token)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	storedUser, err := repos.Users.GetByID(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("GetByID user: %v", err)
+	}
+	checkTimestamps(t, "registered user", user.DateCreated, user.DateUpdated, storedUser.DateCreated, storedUser.DateUpdated)
+
+	other := &models.User{FullName: "John Doe", Email: "john@example.com", PhoneNumber: "+2348000000002", UserType: models.WASTEWARRIOR}
+	err = repos.Users.Create(ctx, other)
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	storedUser, err = repos.Users.GetByID(ctx, other.Id)
+	if err != nil {
+		t.Fatalf("GetByID user: %v", err)
+	}
+	checkTimestamps(t, "created user", other.DateCreated, other.DateUpdated, storedUser.DateCreated, storedUser.DateUpdated)
+
+	partner := &models.Partner{BRNumber: "BR-1", Address: "1 Market Street", Timezone: "UTC", Status: models.PartnerPending, UserID: user.Id}
+	err = repos.Partners.Create(ctx, partner)
+	if err != nil {
+		t.Fatalf("Create partner: %v", err)
+	}
+	storedPartner, err := repos.Partners.GetByID(ctx, partner.ID)
+	if err != nil {
+		t.Fatalf("GetByID partner: %v", err)
+	}
+	checkTimestamps(t, "partner", partner.DateCreated, partner.DateUpdated, storedPartner.DateCreated, storedPartner.DateUpdated)
+
+	product := &models.Product{Name: "Bread", Category: models.CategoryBakery, PartnerID: &partner.ID}
+	err = repos.Products.Create(ctx, product)
+	if err != nil {
+		t.Fatalf("Create product: %v", err)
+	}
+	storedProduct, err := repos.Products.GetByID(ctx, product.Id)
+	if err != nil {
+		t.Fatalf("GetByID product: %v", err)
+	}
+	checkTimestamps(t, "product", product.DateCreated, product.DateUpdated, storedProduct.DateCreated, storedProduct.DateUpdated)
+
+	bag := &models.MagicBag{Title: "Bakery bag", BagPrice: 4.5, Quantity: 3, Status: models.MagicBagActive, PartnerID: partner.ID,
+		Items: []models.MagicBagItem{{ProductID: product.Id, Quantity: 2}}}
+	err = repos.MagicBags.Create(ctx, bag)
+	if err != nil {
+		t.Fatalf("Create bag: %v", err)
+	}
+	storedBag, err := repos.MagicBags.GetByID(ctx, bag.ID)
+	if err != nil {
+		t.Fatalf("GetByID bag: %v", err)
+	}
+	checkTimestamps(t, "bag", bag.DateCreated, bag.DateUpdated, storedBag.DateCreated, storedBag.DateUpdated)
+}
@@ -0,0 +1,161 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"time"
+)
+
+type UserTokenRepository struct {
+	db *database.DB
+}
+
+func (r *UserTokenRepository) Replace(ctx context.Context, token *models.UserToken, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The old row is locked so that attempts recorded on it while the new
+	// code is being stored are not lost.
+	var old models.UserToken
+	var lockedUntil sql.NullTime
+	query := "SELECT id, attempts, locked_until FROM user_tokens WHERE user_id = ? AND purpose = ? " + tx.Dialect.ForUpdate(false)
+	err = tx.QueryRowContext(ctx, query, token.UserID, token.Purpose).Scan(&old.Id, &old.Attempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		query := `
+		INSERT INTO user_tokens (user_id, purpose, email, code_hash, expire_at)
+		VALUES (?, ?, ?, ?, ?)
+		`
+		id, err := tx.Insert(ctx, query, token.UserID, token.Purpose, token.Email, token.CodeHash, token.ExpireAt)
+		if err != nil {
+			return err
+		}
+		token.Id = id
+		token.Attempts, token.LockedUntil = 0, nil
+
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+	if lockedUntil.Valid {
+		old.LockedUntil = &lockedUntil.Time
+	}
+
+	token.Id = old.Id
+	token.CarryAttempts(&old, now)
+
+	query = `
+	UPDATE user_tokens
+	SET email = ?, code_hash = ?, attempts = ?, locked_until = ?, expire_at = ?
+	WHERE id = ?
+	`
+	_, err = tx.ExecContext(ctx, query, token.Email, token.CodeHash, token.Attempts, token.LockedUntil, token.ExpireAt, token.Id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserTokenRepository) GetByUserID(ctx context.Context, userId int64, purpose models.TokenPurpose) (*models.UserToken, error) {
+	query := "SELECT id, user_id, purpose, email, code_hash, attempts, locked_until, expire_at, date_created, date_updated FROM user_tokens WHERE user_id = ? AND purpose = ?"
+
+	var t models.UserToken
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userId, purpose).Scan(&t.Id, &t.UserID, &t.Purpose, &t.Email, &t.CodeHash, &t.Attempts, &lockedUntil, &t.ExpireAt, &t.DateCreated, &t.DateUpdated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrTokenNotFound
+		}
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = &lockedUntil.Time
+	}
+
+	return &t, nil
+}
+
+// RecordAttempt sets locked_until before attempts because MySQL evaluates
+// the assignments in order, with later ones seeing the new values.
+func (r *UserTokenRepository) RecordAttempt(ctx context.Context, id int64, maxAttempts int, lockUntil time.Time) (bool, error) {
+	query := `
+	UPDATE user_tokens
+	SET locked_until = CASE WHEN attempts + 1 >= ? THEN ? ELSE locked_until END, attempts = attempts + 1
+	WHERE id = ? AND attempts < ?
+	`
+	result, err := r.db.ExecContext(ctx, query, maxAttempts, lockUntil, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *UserTokenRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM user_tokens WHERE id = ?", id)
+	return err
+}
+
+type RefreshTokenRepository struct {
+	db *database.DB
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	query := `
+	INSERT INTO refresh_tokens (user_id, token_hash, expire_at)
+	VALUES (?, ?, ?)
+	`
+	id, err := r.db.Insert(ctx, query, token.UserID, token.TokenHash, token.ExpireAt)
+	if err != nil {
+		return err
+	}
+	token.Id = id
+	return nil
+}
+
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	query := "SELECT id, user_id, token_hash, expire_at, revoked_at, date_created, date_updated FROM refresh_tokens WHERE token_hash = ?"
+
+	var t models.RefreshToken
+	var revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(&t.Id, &t.UserID, &t.TokenHash, &t.ExpireAt, &revokedAt, &t.DateCreated, &t.DateUpdated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+
+	return &t, nil
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id int64, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", at, id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *RefreshTokenRepository) RevokeByHash(ctx context.Context, hash string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL", at, hash)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userId int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", at, userId)
+	return err
+}
@@ -0,0 +1,201 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"time"
+)
+
+const transactionColumns = "id, amount, payment_type, payment_status, payment_reference, purchase_day, picked_up_at, date_created, date_updated, user_id, partner_id, magic_bag_id"
+
+func scanTransaction(row scanner) (*models.Transaction, error) {
+	var t models.Transaction
+	var purchaseDay time.Time
+	var reference sql.NullString
+	var pickedUpAt sql.NullTime
+
+	err := row.Scan(&t.Id, &t.Amount, &t.PaymentType, &t.PaymentStatus, &reference, &purchaseDay, &pickedUpAt, &t.DateCreated, &t.DateUpdated, &t.UserID, &t.PartnerID, &t.MagicBagID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	t.PaymentReference = reference.String
+	t.PurchaseDay = purchaseDay.Format(time.DateOnly)
+	if pickedUpAt.Valid {
+		t.PickedUpAt = &pickedUpAt.Time
+	}
+
+	return &t, nil
+}
+
+var transactionTable = listTable[*models.Transaction]{
+	spec: models.TransactionListing,
+	columns: map[string]string{
+		"id":             "id",
+		"partner_id":     "partner_id",
+		"magic_bag_id":   "magic_bag_id",
+		"amount":         "amount",
+		"payment_type":   "payment_type",
+		"payment_status": "payment_status",
+		"purchase_day":   "purchase_day",
+		"picked_up_at":   "picked_up_at",
+		"date_created":   "date_created",
+	},
+	selects: transactionColumns,
+	scan:    scanTransaction,
+}
+
+type TransactionRepository struct {
+	db *database.DB
+}
+
+func (r *TransactionRepository) Reserve(ctx context.Context, userId, bagId int64, paymentType models.PaymentType, now time.Time) (*models.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the buyer serialises their purchases so the daily limit check
+	// below cannot race with another request from the same warrior.
+	var lockedId int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = ? "+r.db.Dialect.ForUpdate(false), userId).Scan(&lockedId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM magic_bags WHERE id = ? %s", magicBagColumns, r.db.Dialect.ForUpdate(false))
+	bag, err := scanMagicBag(tx.QueryRowContext(ctx, query, bagId))
+	if err != nil {
+		return nil, err
+	}
+	if bag.Status != models.MagicBagActive {
+		return nil, models.ErrMagicBagWithdrawn
+	}
+	if bag.PickupEnded(now) {
+		return nil, models.ErrPickupEnded
+	}
+	if bag.Quantity <= 0 {
+		return nil, models.ErrMagicBagSoldOut
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM partners WHERE id = ?", partnerColumns)
+	partner, err := scanPartner(tx.QueryRowContext(ctx, query, bag.PartnerID))
+	if err != nil {
+		return nil, err
+	}
+	if partner.Status != models.PartnerApproved {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM users WHERE id = ?", userColumns)
+	seller, err := scanUser(tx.QueryRowContext(ctx, query, partner.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if !seller.Sells() {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	purchaseDay := now.In(partner.Location()).Format(time.DateOnly)
+
+	// Failed and refunded purchases do not count towards the limit.
+	var existing int
+	query = `
+	SELECT COUNT(*) FROM transactions
+	WHERE user_id = ? AND partner_id = ? AND purchase_day = ? AND payment_status NOT IN (?, ?)
+	`
+	err = tx.QueryRowContext(ctx, query, userId, partner.ID, purchaseDay, payment.StatusFailed, payment.StatusRefunded).Scan(&existing)
+	if err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, models.ErrDailyPurchaseLimit
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE magic_bags SET quantity = quantity - 1 WHERE id = ?", bag.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &models.Transaction{
+		Amount:        bag.BagPrice,
+		PaymentType:   paymentType,
+		PaymentStatus: payment.StatusPending,
+		PurchaseDay:   purchaseDay,
+		UserID:        userId,
+		PartnerID:     partner.ID,
+		MagicBagID:    bag.ID,
+	}
+
+	query = `
+	INSERT INTO transactions (amount, payment_type, payment_status, magic_bag_id, user_id, partner_id, purchase_day)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(ctx, query, transaction.Amount, transaction.PaymentType, transaction.PaymentStatus, transaction.MagicBagID, transaction.UserID, transaction.PartnerID, transaction.PurchaseDay)
+	if err != nil {
+		return nil, err
+	}
+	transaction.Id = id
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.DateCreated = now
+	transaction.DateUpdated = now
+	return transaction, nil
+}
+
+func (r *TransactionRepository) UpdatePayment(ctx context.Context, transaction *models.Transaction, from payment.Status, releaseBag bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE transactions SET payment_status = ?, payment_reference = ?, picked_up_at = ?
+	WHERE id = ? AND payment_status = ?
+	`
+	reference := sql.NullString{String: transaction.PaymentReference, Valid: transaction.PaymentReference != ""}
+	result, err := tx.ExecContext(ctx, query, transaction.PaymentStatus, reference, transaction.PickedUpAt, transaction.Id, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrPaymentState
+	}
+
+	if releaseBag {
+		_, err = tx.ExecContext(ctx, "UPDATE magic_bags SET quantity = quantity + 1 WHERE id = ?", transaction.MagicBagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *TransactionRepository) GetByID(ctx context.Context, id int64) (*models.Transaction, error) {
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE id = ?", transactionColumns)
+	return scanTransaction(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *TransactionRepository) GetByReference(ctx context.Context, reference string) (*models.Transaction, error) {
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE payment_reference = ?", transactionColumns)
+	return scanTransaction(r.db.QueryRowContext(ctx, query, reference))
+}
+
+func (r *TransactionRepository) ListByUser(ctx context.Context, userId int64, q listing.Query) ([]*models.Transaction, listing.Page, error) {
+	return transactionTable.list(ctx, r.db, q, "transactions", "user_id = ?", userId)
+}
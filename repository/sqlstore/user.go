@@ -0,0 +1,212 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/database"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+)
+
+const userColumns = "id, fullname, email, phone_number, user_type, status, suspended_at, deleted_at, password_reset_required_at, date_created, date_updated"
+
+// scanUser reads userColumns followed by the columns of extra.
+func scanUser(row scanner, extra ...any) (*models.User, error) {
+	var u models.User
+	var suspendedAt, deletedAt, resetAt sql.NullTime
+
+	dest := []any{&u.Id, &u.FullName, &u.Email, &u.PhoneNumber, &u.UserType, &u.Status, &suspendedAt, &deletedAt, &resetAt, &u.DateCreated, &u.DateUpdated}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if suspendedAt.Valid {
+		u.SuspendedAt = &suspendedAt.Time
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	if resetAt.Valid {
+		u.PasswordResetRequiredAt = &resetAt.Time
+	}
+
+	return &u, nil
+}
+
+var userTable = listTable[*models.User]{
+	spec: models.UserListing,
+	columns: map[string]string{
+		"id":           "id",
+		"fullname":     "fullname",
+		"email":        "email",
+		"user_type":    "user_type",
+		"status":       "status",
+		"suspended":    "(suspended_at IS NOT NULL)",
+		"deleted":      "(deleted_at IS NOT NULL)",
+		"date_created": "date_created",
+	},
+	selects: userColumns,
+	scan:    func(row scanner) (*models.User, error) { return scanUser(row) },
+}
+
+type UserRepository struct {
+	db *database.DB
+}
+
+const insertUser = `
+	INSERT INTO users (fullname, email, password, phone_number, user_type)
+	VALUES (?, ?, ?, ?, ?)
+	`
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	id, err := r.db.Insert(ctx, insertUser, user.FullName, user.Email, user.PasswordHash, user.PhoneNumber, user.UserType)
+	if err != nil {
+		return r.insertError(ctx, err, user)
+	}
+	user.Id = id
+	user.Status = models.UserInactive
+	return loadTimestamps(ctx, r.db, "users", id, &user.DateCreated, &user.DateUpdated)
+}
+
+func (r *UserRepository) Register(ctx context.Context, user *models.User, token *models.UserToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.Insert(ctx, insertUser, user.FullName, user.Email, user.PasswordHash, user.PhoneNumber, user.UserType)
+	if err != nil {
+		// insertError queries outside the transaction, which must be over
+		// first on SQLite since it only has the one connection.
+		tx.Rollback()
+		return r.insertError(ctx, err, user)
+	}
+
+	query := `
+	INSERT INTO user_tokens (user_id, purpose, email, code_hash, expire_at)
+	VALUES (?, ?, ?, ?, ?)
+	`
+	tokenId, err := tx.Insert(ctx, query, id, token.Purpose, token.Email, token.CodeHash, token.ExpireAt)
+	if err != nil {
+		return err
+	}
+
+	err = loadTimestamps(ctx, tx, "users", id, &user.DateCreated, &user.DateUpdated)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	user.Id = id
+	user.Status = models.UserInactive
+	token.Id = tokenId
+	token.UserID = id
+	return nil
+}
+
+// insertError tells which of the unique columns an insert of user collided
+// on. Every database words the error differently, and the email and phone
+// number may each contain the other's column name, so it asks rather than
+// reading the message.
+func (r *UserRepository) insertError(ctx context.Context, err error, user *models.User) error {
+	if !r.db.Dialect.IsUniqueViolation(err) {
+		return err
+	}
+
+	var taken int
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+r.db.Dialect.EqualFold("email"), user.Email).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return models.ErrDuplicateEmail
+	}
+	return models.ErrDuplicatePhone
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = ?", userColumns)
+	return scanUser(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s, password FROM users WHERE %s", userColumns, r.db.Dialect.EqualFold("email"))
+
+	var password sql.NullString
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email), &password)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = password.String
+
+	return user, nil
+}
+
+func (r *UserRepository) List(ctx context.Context, q listing.Query) ([]*models.User, listing.Page, error) {
+	return userTable.list(ctx, r.db, q, "users", "")
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET status = ? WHERE id = ?", status, id)
+	return err
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET password = ?, password_reset_required_at = NULL WHERE id = ?", passwordHash, id)
+	return err
+}
+
+func (r *UserRepository) UpdateEmail(ctx context.Context, id int64, email string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET email = ? WHERE id = ?", email, id)
+	if err != nil && r.db.Dialect.IsUniqueViolation(err) {
+		return models.ErrDuplicateEmail
+	}
+	return err
+}
+
+func (r *UserRepository) UpdateAccount(ctx context.Context, user *models.User, entry *models.AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE users SET user_type = ?, suspended_at = ?, deleted_at = ?, password_reset_required_at = ?
+	WHERE id = ? AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, user.UserType, user.SuspendedAt, user.DeletedAt, user.PasswordResetRequiredAt, user.Id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrUserDeleted
+	}
+
+	query = `
+	INSERT INTO audit_log (actor_id, target_user_id, action, old_values, new_values, reason)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(ctx, query, entry.ActorID, entry.TargetUserID, entry.Action, string(entry.OldValues), string(entry.NewValues), entry.Reason)
+	if err != nil {
+		return err
+	}
+	entry.Id = id
+
+	return tx.Commit()
+}
@@ -3,36 +3,111 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/horlathunbhosun/reducing-food-waste/api/handlers"
+	"github.com/horlathunbhosun/reducing-food-waste/api/middleware"
+	"github.com/horlathunbhosun/reducing-food-waste/app"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
 	"github.com/horlathunbhosun/reducing-food-waste/pkg/response"
 	"net/http"
 )
 
-func RegisterRoutes(server *gin.Engine) {
+func RegisterRoutes(server *gin.Engine, container *app.Container) {
+	h := handlers.New(container)
+
+	server.Use(
+		middleware.RequestID(container.Logger),
+		middleware.AccessLog("/healthz", "/readyz", "/metrics"),
+		middleware.Metrics(container.Metrics),
+		middleware.Errors(container.Config.Server.ErrorFormat),
+		middleware.Recover(),
+	)
 
-	var responseBody response.JsonResponse
 	server.NoRoute(func(c *gin.Context) {
-		responseBody.Error = true
-		responseBody.Message = "Route not found"
-		responseBody.Status = false
-		c.JSON(http.StatusNotFound, responseBody)
+		c.Error(models.ErrRouteNotFound)
 	})
 
 	server.NoMethod(func(c *gin.Context) {
-		responseBody.Error = true
-		responseBody.Message = "Method not allowed"
-		responseBody.Status = false
-		c.JSON(http.StatusMethodNotAllowed, responseBody)
+		c.Error(models.ErrMethodNotAllowed)
 	})
 
+	// Probes live outside /v1 so they do not change with the API version.
+	server.GET("/healthz", h.Health)
+	server.GET("/readyz", h.Ready)
+	server.GET("/version", h.Version)
+	server.GET("/metrics", gin.WrapH(container.Metrics.Handler()))
+
 	v1 := server.Group("/v1")
 	v1.GET("/", func(c *gin.Context) {
+		var responseBody response.JsonResponse
 		responseBody.Message = "Welcome to Waste Warrior API"
 		responseBody.Status = true
 		c.JSON(http.StatusOK, responseBody)
 	})
-	v1.POST("/register", handlers.Signup)
-	v1.PATCH("/verify-token/:token", handlers.VerificationToken)
-	v1.POST("/reset-token", handlers.ResetToken)
+	v1.POST("/register", h.Signup)
+	v1.PATCH("/verify-token", h.VerificationToken)
+	v1.POST("/reset-token", h.ResetToken)
+	v1.POST("/forgot-password", h.ForgotPassword)
+	v1.POST("/reset-password", h.ResetPassword)
+	v1.POST("/login", h.Login)
+	v1.POST("/refresh-token", h.RefreshToken)
+	v1.POST("/logout", h.Logout)
+
+	v1.GET("/products", h.ListProducts)
+	v1.GET("/products/:id", h.GetProduct)
+	v1.GET("/magic-bags", h.ListMagicBags)
+	v1.GET("/magic-bags/:id", h.GetMagicBag)
+	v1.POST("/payments/webhook", h.PaymentWebhook)
+	v1.GET("/partners/:id", h.GetPublicPartner)
+	v1.GET("/partners/:id/feedback", h.ListPartnerFeedback)
+
+	authenticated := v1.Group("/", middleware.Authenticate(container.Auth))
+	authenticated.GET("/me", h.Me)
+	authenticated.POST("/me/email", h.ChangeEmail)
+	authenticated.POST("/me/email/confirm", h.ConfirmEmailChange)
+
+	warrior := authenticated.Group("", middleware.Authorize(models.WASTEWARRIOR))
+	warrior.POST("/magic-bags/:id/purchase", h.PurchaseMagicBag)
+	warrior.GET("/transactions", h.ListTransactions)
+	warrior.GET("/transactions/:id", h.GetTransaction)
+	warrior.POST("/transactions/:id/feedback", h.CreateFeedback)
+	warrior.PUT("/transactions/:id/feedback", h.UpdateFeedback)
+	warrior.DELETE("/transactions/:id/feedback", h.DeleteFeedback)
+
+	partner := authenticated.Group("/partner", middleware.Authorize(models.PARTNERS))
+	partner.POST("/profile", h.CreatePartnerProfile)
+	partner.PUT("/profile", h.UpdatePartnerProfile)
+	partner.GET("/profile", h.GetPartnerProfile)
+
+	partnerBags := partner.Group("/magic-bags", middleware.RequireApprovedPartner(container.Partners))
+	partnerBags.POST("", h.CreateMagicBag)
+	partnerBags.GET("", h.ListOwnMagicBags)
+	partnerBags.GET("/:id", h.GetOwnMagicBag)
+	partnerBags.PUT("/:id", h.UpdateMagicBag)
+	partnerBags.DELETE("/:id", h.WithdrawMagicBag)
+
+	partnerProducts := partner.Group("/products", middleware.RequireApprovedPartner(container.Partners))
+	partnerProducts.GET("", h.ListUsableProducts)
+	partnerProducts.POST("", h.CreatePartnerProduct)
+	partnerProducts.PUT("/:id", h.UpdatePartnerProduct)
+	partnerProducts.DELETE("/:id", h.DeletePartnerProduct)
+
+	partner.PATCH("/transactions/:id/pickup", middleware.RequireApprovedPartner(container.Partners), h.MarkTransactionPickedUp)
 
-	v1.GET("/products")
+	admin := authenticated.Group("/admin", middleware.Authorize(models.ADMIN))
+	admin.GET("/partners", h.ListPartners)
+	admin.PATCH("/partners/:id/approve", h.ApprovePartner)
+	admin.PATCH("/partners/:id/reject", h.RejectPartner)
+	admin.POST("/transactions/:id/refund", h.RefundTransaction)
+	admin.DELETE("/feedback/:id", h.RemoveFeedback)
+	admin.POST("/products", h.CreateProduct)
+	admin.PUT("/products/:id", h.UpdateProduct)
+	admin.DELETE("/products/:id", h.DeleteProduct)
+	admin.GET("/users", h.ListUsers)
+	admin.GET("/users/:id", h.GetUser)
+	admin.GET("/users/:id/transactions", h.ListUserTransactions)
+	admin.PATCH("/users/:id/suspend", h.SuspendUser)
+	admin.PATCH("/users/:id/reactivate", h.ReactivateUser)
+	admin.PATCH("/users/:id/force-password-reset", h.ForcePasswordReset)
+	admin.PATCH("/users/:id/type", h.ChangeUserType)
+	admin.DELETE("/users/:id", h.DeleteUser)
+	admin.GET("/audit-log", h.ListAuditLog)
 }
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"time"
+)
+
+// AdminService lets admins look after user accounts. Every change is
+// recorded in the audit log with the admin who made it and their reason.
+type AdminService struct {
+	users         repository.UserRepository
+	partners      repository.PartnerRepository
+	transactions  repository.TransactionRepository
+	refreshTokens repository.RefreshTokenRepository
+	auditLog      repository.AuditLogRepository
+	auth          *AuthService
+}
+
+func NewAdminService(repos repository.Repositories, auth *AuthService) *AdminService {
+	return &AdminService{
+		users:         repos.Users,
+		partners:      repos.Partners,
+		transactions:  repos.Transactions,
+		refreshTokens: repos.RefreshTokens,
+		auditLog:      repos.AuditLog,
+		auth:          auth,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, q listing.Query) ([]*models.User, listing.Page, error) {
+	return s.users.List(ctx, q)
+}
+
+// GetUser loads the user together with their partner profile, if they have
+// one.
+func (s *AdminService) GetUser(ctx context.Context, id int64) (*models.UserDetails, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	partner, err := s.partners.GetByUserID(ctx, id)
+	if err != nil && !errors.Is(err, models.ErrPartnerNotFound) {
+		return nil, err
+	}
+
+	return &models.UserDetails{User: user, Partner: partner}, nil
+}
+
+func (s *AdminService) ListUserTransactions(ctx context.Context, id int64, q listing.Query) ([]*models.Transaction, listing.Page, error) {
+	_, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, listing.Page{}, err
+	}
+	return s.transactions.ListByUser(ctx, id, q)
+}
+
+func (s *AdminService) AuditLog(ctx context.Context, q listing.Query) ([]*models.AuditEntry, listing.Page, error) {
+	return s.auditLog.List(ctx, q)
+}
+
+// Suspend stops the user from signing in and ends their sessions until they
+// are reactivated.
+func (s *AdminService) Suspend(ctx context.Context, actor *models.User, id int64, reason string) (*models.User, error) {
+	user, err := s.change(ctx, actor, id, models.AuditSuspendUser, reason, func(user *models.User, now time.Time) error {
+		if user.SuspendedAt != nil {
+			return models.ErrUserSuspended
+		}
+		user.SuspendedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, s.refreshTokens.RevokeAllForUser(ctx, user.Id, time.Now())
+}
+
+func (s *AdminService) Reactivate(ctx context.Context, actor *models.User, id int64, reason string) (*models.User, error) {
+	return s.change(ctx, actor, id, models.AuditReactivateUser, reason, func(user *models.User, now time.Time) error {
+		if user.SuspendedAt == nil {
+			return models.ErrUserNotSuspended
+		}
+		user.SuspendedAt = nil
+		return nil
+	})
+}
+
+// ForcePasswordReset ends the user's sessions and refuses them until they
+// set a new password with the reset code it emails them.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actor *models.User, id int64, reason string) (*models.User, error) {
+	user, err := s.change(ctx, actor, id, models.AuditForcePasswordReset, reason, func(user *models.User, now time.Time) error {
+		user.PasswordResetRequiredAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.refreshTokens.RevokeAllForUser(ctx, user.Id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// While resets are locked the user cannot be sent a code, but they can
+	// ask for one with forgot-password once the lockout ends.
+	err = s.auth.sendCode(ctx, user, models.PurposeResetPassword, user.Email, "password_reset_required.html")
+	if err != nil && !errors.Is(err, models.ErrVerificationLocked) {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *AdminService) ChangeUserType(ctx context.Context, actor *models.User, id int64, userType models.UserType, reason string) (*models.User, error) {
+	return s.change(ctx, actor, id, models.AuditChangeUserType, reason, func(user *models.User, now time.Time) error {
+		if user.UserType == userType {
+			return models.ErrUserTypeUnchanged
+		}
+		user.UserType = userType
+		return nil
+	})
+}
+
+// Delete soft deletes the user and ends their sessions. The account and its
+// history are kept, but the user is treated as unknown from then on.
+func (s *AdminService) Delete(ctx context.Context, actor *models.User, id int64, reason string) (*models.User, error) {
+	user, err := s.change(ctx, actor, id, models.AuditDeleteUser, reason, func(user *models.User, now time.Time) error {
+		user.DeletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, s.refreshTokens.RevokeAllForUser(ctx, user.Id, time.Now())
+}
+
+// change lets apply update the account of the user with the id and saves it
+// with an audit entry of the action. Admins cannot change their own account,
+// so that they cannot lock themselves out, nor change deleted ones.
+func (s *AdminService) change(ctx context.Context, actor *models.User, id int64, action models.AuditAction, reason string, apply func(user *models.User, now time.Time) error) (*models.User, error) {
+	if actor.Id == id {
+		return nil, models.ErrOwnAccount
+	}
+
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, models.ErrUserDeleted
+	}
+
+	entry := &models.AuditEntry{
+		ActorID:      actor.Id,
+		TargetUserID: user.Id,
+		Action:       action,
+		OldValues:    models.AccountValues(user),
+		Reason:       reason,
+	}
+
+	err = apply(user, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	entry.NewValues = models.AccountValues(user)
+
+	err = s.users.UpdateAccount(ctx, user, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"testing"
+	"time"
+)
+
+func newTestAdmin(t *testing.T, repos repository.Repositories) (*AdminService, *models.User) {
+	t.Helper()
+
+	auth := NewAuthService(repos, mailer.NewCaptureSender(), config.AuthConfig{
+		JWTSecret:               "test-secret",
+		AccessTokenTTL:          time.Minute,
+		RefreshTokenTTL:         time.Hour,
+		VerificationCodeTTL:     time.Minute,
+		VerificationMaxAttempts: testMaxAttempts,
+		VerificationLockout:     time.Hour,
+	})
+
+	admin := &models.User{
+		FullName:    "Admin",
+		Email:       "admin@example.com",
+		PhoneNumber: nextPhone(),
+		UserType:    models.ADMIN,
+		Status:      models.UserActive,
+	}
+	err := repos.Users.Create(context.Background(), admin)
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	return NewAdminService(repos, auth), admin
+}
+
+func TestAdminActionsAreAudited(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+	admin, actor := newTestAdmin(t, repos)
+	user := createWarrior(t, repos, "jane")
+
+	_, err := admin.Suspend(ctx, actor, actor.Id, "locking myself out")
+	if !errors.Is(err, models.ErrOwnAccount) {
+		t.Fatalf("suspending own account: got %v, want ErrOwnAccount", err)
+	}
+
+	steps := []struct {
+		action models.AuditAction
+		run    func(reason string) (*models.User, error)
+	}{
+		{models.AuditSuspendUser, func(reason string) (*models.User, error) {
+			return admin.Suspend(ctx, actor, user.Id, reason)
+		}},
+		{models.AuditReactivateUser, func(reason string) (*models.User, error) {
+			return admin.Reactivate(ctx, actor, user.Id, reason)
+		}},
+		{models.AuditForcePasswordReset, func(reason string) (*models.User, error) {
+			return admin.ForcePasswordReset(ctx, actor, user.Id, reason)
+		}},
+		{models.AuditChangeUserType, func(reason string) (*models.User, error) {
+			return admin.ChangeUserType(ctx, actor, user.Id, models.PARTNERS, reason)
+		}},
+		{models.AuditDeleteUser, func(reason string) (*models.User, error) {
+			return admin.Delete(ctx, actor, user.Id, reason)
+		}},
+	}
+	for _, step := range steps {
+		_, err := step.run("reason for " + string(step.action))
+		if err != nil {
+			t.Fatalf("%s: %v", step.action, err)
+		}
+	}
+
+	entries, _, err := repos.AuditLog.List(ctx, listing.Query{Limit: listing.DefaultLimit, Sort: []listing.Sort{{Field: "id"}}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != len(steps) {
+		t.Fatalf("got %d audit entries, want %d", len(entries), len(steps))
+	}
+	for i, entry := range entries {
+		action := steps[i].action
+		if entry.Action != action || entry.ActorID != actor.Id || entry.TargetUserID != user.Id || entry.Reason != "reason for "+string(action) {
+			t.Errorf("entry %d: got %s by %d on %d (%q), want %s by %d on %d", i, entry.Action, entry.ActorID, entry.TargetUserID, entry.Reason, action, actor.Id, user.Id)
+		}
+	}
+
+	var before, after map[string]any
+	json.Unmarshal(entries[0].OldValues, &before)
+	json.Unmarshal(entries[0].NewValues, &after)
+	if before["suspended_at"] != nil || after["suspended_at"] == nil {
+		t.Errorf("suspend entry: got suspended_at %v -> %v, want it set by the action", before["suspended_at"], after["suspended_at"])
+	}
+	json.Unmarshal(entries[3].OldValues, &before)
+	json.Unmarshal(entries[3].NewValues, &after)
+	if before["user_type"] != string(models.WASTEWARRIOR) || after["user_type"] != string(models.PARTNERS) {
+		t.Errorf("user type entry: got %v -> %v", before["user_type"], after["user_type"])
+	}
+}
+
+func TestPartnerBagsUnavailableAfterAdminAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		action func(admin *AdminService, actor *models.User, id int64) (*models.User, error)
+	}{
+		{"suspended", func(admin *AdminService, actor *models.User, id int64) (*models.User, error) {
+			return admin.Suspend(context.Background(), actor, id, "fraud")
+		}},
+		{"deleted", func(admin *AdminService, actor *models.User, id int64) (*models.User, error) {
+			return admin.Delete(context.Background(), actor, id, "closed down")
+		}},
+		{"no longer a partner", func(admin *AdminService, actor *models.User, id int64) (*models.User, error) {
+			return admin.ChangeUserType(context.Background(), actor, id, models.WASTEWARRIOR, "signed up as the wrong type")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			purchases, repos := newTestPurchases(t)
+			bags := NewMagicBagService(repos, nopRecorder{})
+			admin, actor := newTestAdmin(t, repos)
+
+			warrior := createWarrior(t, repos, "jane")
+			partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+			bag := createBag(t, repos, partner.ID, 5)
+
+			_, err := tt.action(admin, actor, partner.UserID)
+			if err != nil {
+				t.Fatalf("admin action: %v", err)
+			}
+
+			listed, _, err := bags.ListAvailable(ctx, listing.Query{Limit: listing.DefaultLimit, Sort: []listing.Sort{{Field: "id"}}})
+			if err != nil {
+				t.Fatalf("ListAvailable: %v", err)
+			}
+			if len(listed) != 0 {
+				t.Errorf("got %d bags listed, want none", len(listed))
+			}
+			_, err = bags.GetAvailable(ctx, bag.ID)
+			if !errors.Is(err, models.ErrMagicBagNotFound) {
+				t.Errorf("GetAvailable: got %v, want ErrMagicBagNotFound", err)
+			}
+			_, err = purchases.Purchase(ctx, warrior.Id, bag.ID, models.CASH, "")
+			if !errors.Is(err, models.ErrMagicBagNotFound) {
+				t.Errorf("Purchase: got %v, want ErrMagicBagNotFound", err)
+			}
+		})
+	}
+}
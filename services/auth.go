@@ -0,0 +1,488 @@
+// Package services holds the business rules of the API. Services only talk to
+// storage through the repository interfaces, so they work the same on top of
+// MySQL and the in-memory store.
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/pkg/utility"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"strconv"
+	"time"
+)
+
+// AuthService handles the codes emailed to users, which verify addresses,
+// reset passwords and change emails, and issues and checks the tokens
+// users sign in with.
+type AuthService struct {
+	users         repository.UserRepository
+	userTokens    repository.UserTokenRepository
+	refreshTokens repository.RefreshTokenRepository
+	mail          mailer.Sender
+	config        config.AuthConfig
+}
+
+func NewAuthService(repos repository.Repositories, mail mailer.Sender, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		users:         repos.Users,
+		userTokens:    repos.UserTokens,
+		refreshTokens: repos.RefreshTokens,
+		mail:          mail,
+		config:        cfg,
+	}
+}
+
+// Signup stores a new inactive user together with the code to verify their
+// email, both or neither, and only then queues the email with the code, so a
+// failed signup never sends one. A taken email or phone number returns
+// models.ErrDuplicateEmail or models.ErrDuplicatePhone. Should queueing fail,
+// the account is kept and a new code can be asked for.
+func (s *AuthService) Signup(ctx context.Context, req *models.SignupRequest) (*models.User, error) {
+	hash, err := utility.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		FullName:     req.FullName,
+		Email:        req.Email,
+		PasswordHash: hash,
+		PhoneNumber:  req.PhoneNumber,
+		UserType:     req.UserType,
+	}
+
+	userToken, code, err := s.newCode(models.PurposeVerifyEmail, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.Register(ctx, user, userToken)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	err = s.mailCode(user, userToken, code, "user_token.html")
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SendVerificationCode emails the user a code to verify their address with.
+func (s *AuthService) SendVerificationCode(ctx context.Context, user *models.User) error {
+	return s.sendCode(ctx, user, models.PurposeVerifyEmail, user.Email, "user_token.html")
+}
+
+// ResendVerificationCode sends a fresh code to the user with the email. Like
+// ForgotPassword it reports nothing about the email: an unknown email, one
+// that is already verified or one whose verification is locked is quietly
+// ignored.
+func (s *AuthService) ResendVerificationCode(ctx context.Context, email string) error {
+	user, err := s.userByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status == models.UserActive {
+		return nil
+	}
+
+	err = s.SendVerificationCode(ctx, user)
+	if errors.Is(err, models.ErrVerificationLocked) {
+		return nil
+	}
+	return err
+}
+
+// VerifyCode activates the account with the email when the code is the
+// verification code last sent to it, and uses the code up.
+func (s *AuthService) VerifyCode(ctx context.Context, email, code string) error {
+	user, err := s.userByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return models.ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+
+	userToken, err := s.checkCode(ctx, user.Id, models.PurposeVerifyEmail, code)
+	if err != nil {
+		return err
+	}
+
+	err = s.users.UpdateStatus(ctx, user.Id, models.UserActive)
+	if err != nil {
+		return err
+	}
+
+	return s.userTokens.Delete(ctx, userToken.Id)
+}
+
+// ForgotPassword emails a password reset code to the user with the email.
+// It reports nothing about the email, so that it cannot be used to find out
+// who has an account: an unknown email, or one whose reset is locked, is
+// quietly ignored.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.sendCode(ctx, user, models.PurposeResetPassword, user.Email, "password_reset.html")
+	if errors.Is(err, models.ErrVerificationLocked) {
+		return nil
+	}
+	return err
+}
+
+// ResetPassword sets a new password for the user with the email when the code
+// is the password reset code last sent to them. Every session the user has is
+// ended, since whoever knew the old password may hold one.
+func (s *AuthService) ResetPassword(ctx context.Context, email, code, password string) error {
+	user, err := s.userByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return models.ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+
+	userToken, err := s.checkCode(ctx, user.Id, models.PurposeResetPassword, code)
+	if err != nil {
+		return err
+	}
+
+	hash, err := utility.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.users.UpdatePassword(ctx, user.Id, hash)
+	if err != nil {
+		return err
+	}
+
+	err = s.refreshTokens.RevokeAllForUser(ctx, user.Id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.userTokens.Delete(ctx, userToken.Id)
+}
+
+// RequestEmailChange emails a code to newEmail, which the user confirms the
+// address with before it replaces theirs. The user's password is checked
+// first.
+func (s *AuthService) RequestEmailChange(ctx context.Context, user *models.User, newEmail, password string) error {
+	current, err := s.users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+	if !utility.CompareHashedPassword(password, current.PasswordHash) {
+		return models.ErrInvalidCredentials
+	}
+
+	_, err = s.users.GetByEmail(ctx, newEmail)
+	if err == nil {
+		return models.ErrDuplicateEmail
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return err
+	}
+
+	return s.sendCode(ctx, user, models.PurposeChangeEmail, newEmail, "email_change.html")
+}
+
+// ConfirmEmailChange switches the user to the address the email change code
+// was sent to, and lets the old address know.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, user *models.User, code string) (*models.User, error) {
+	userToken, err := s.checkCode(ctx, user.Id, models.PurposeChangeEmail, code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.UpdateEmail(ctx, user.Id, userToken.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userTokens.Delete(ctx, userToken.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"userName": user.FullName,
+		"newEmail": userToken.Email,
+	}
+	err = s.mail.Send(user.Email, "email_changed.html", data)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := *user
+	changed.Email = userToken.Email
+	return &changed, nil
+}
+
+// sendCode replaces the user's code for the purpose with a new one and emails
+// it to the address with the template. The email is queued in the outbox, so
+// the code is delivered even if the mail server is briefly unavailable. The
+// new code inherits the attempts made on the old one, and none is sent while
+// the purpose is locked.
+func (s *AuthService) sendCode(ctx context.Context, user *models.User, purpose models.TokenPurpose, email, template string) error {
+	existing, err := s.userTokens.GetByUserID(ctx, user.Id, purpose)
+	if err != nil && !errors.Is(err, models.ErrTokenNotFound) {
+		return err
+	}
+	if existing != nil && existing.Locked(time.Now()) {
+		return models.ErrVerificationLocked
+	}
+
+	userToken, code, err := s.newCode(purpose, email)
+	if err != nil {
+		return err
+	}
+	userToken.UserID = user.Id
+
+	err = s.userTokens.Replace(ctx, userToken, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.mailCode(user, userToken, code, template)
+}
+
+// newCode generates a code for the purpose, to be sent to email, and returns
+// it with the token to store for it. The caller sets the user.
+func (s *AuthService) newCode(purpose models.TokenPurpose, email string) (*models.UserToken, string, error) {
+	code, err := utility.GenerateCode(models.VerificationCodeDigits)
+	if err != nil {
+		return nil, "", err
+	}
+
+	userToken := &models.UserToken{
+		Purpose:  purpose,
+		Email:    email,
+		CodeHash: s.hashCode(email, code),
+		ExpireAt: time.Now().Add(s.config.VerificationCodeTTL),
+	}
+	return userToken, code, nil
+}
+
+func (s *AuthService) mailCode(user *models.User, userToken *models.UserToken, code, template string) error {
+	data := map[string]interface{}{
+		"userName": user.FullName,
+		"email":    userToken.Email,
+		"Code":     code,
+		// The outbox stores the data as JSON, so times go in formatted.
+		"ExpireAt": userToken.ExpireAt.UTC().Format("15:04 MST on 2 Jan 2006"),
+	}
+	return s.mail.Send(userToken.Email, template, data)
+}
+
+// checkCode returns the user's code for the purpose when code matches it, for
+// the caller to act on and then delete. Every try counts against the code,
+// and once VerificationMaxAttempts are spent the purpose is locked for
+// VerificationLockout. A user without a code gets the same error as a wrong
+// code.
+func (s *AuthService) checkCode(ctx context.Context, userId int64, purpose models.TokenPurpose, code string) (*models.UserToken, error) {
+	userToken, err := s.userTokens.GetByUserID(ctx, userId, purpose)
+	if errors.Is(err, models.ErrTokenNotFound) {
+		return nil, models.ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if userToken.Locked(now) {
+		return nil, models.ErrVerificationLocked
+	}
+	if now.After(userToken.ExpireAt) || userToken.Attempts >= s.config.VerificationMaxAttempts {
+		return nil, models.ErrTokenExpired
+	}
+
+	counted, err := s.userTokens.RecordAttempt(ctx, userToken.Id, s.config.VerificationMaxAttempts, now.Add(s.config.VerificationLockout))
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		return nil, models.ErrVerificationLocked
+	}
+
+	if !hmac.Equal([]byte(s.hashCode(userToken.Email, code)), []byte(userToken.CodeHash)) {
+		return nil, models.ErrInvalidCode
+	}
+
+	return userToken, nil
+}
+
+// userByEmail looks up the user with the email, treating deleted users as
+// unknown so that none of the email flows reach them.
+func (s *AuthService) userByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// hashCode keys the code hash with the JWT secret, so changing the secret
+// also invalidates the codes that are out. The hash is bound to the address
+// the code went to rather than to the user id, so that it can be made before
+// a new user has an id.
+func (s *AuthService) hashCode(email, code string) string {
+	return utility.HashCode([]byte(s.config.JWTSecret), email, code)
+}
+
+// Login checks the email and password and starts a new session. Accounts
+// that have not verified their email, are suspended or must reset their
+// password are refused.
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.User, *models.TokenPair, error) {
+	user, err := s.userByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, nil, models.ErrInvalidCredentials
+		}
+		return nil, nil, err
+	}
+
+	if !utility.CompareHashedPassword(password, user.PasswordHash) {
+		return nil, nil, models.ErrInvalidCredentials
+	}
+	user.PasswordHash = ""
+
+	err = user.AccessError()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.issueTokenPair(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, pair, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is revoked so it can only be used once; presenting an already revoked token
+// revokes every session the user has, since it means the token was leaked.
+func (s *AuthService) Refresh(ctx context.Context, plain string) (*models.User, *models.TokenPair, error) {
+	token, err := s.refreshTokens.GetByHash(ctx, utility.HashToken(plain))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		_ = s.refreshTokens.RevokeAllForUser(ctx, token.UserID, now)
+		return nil, nil, models.ErrInvalidRefreshToken
+	}
+
+	if now.After(token.ExpireAt) {
+		return nil, nil, models.ErrInvalidRefreshToken
+	}
+
+	// A concurrent request rotated the same token first.
+	revoked, err := s.refreshTokens.Revoke(ctx, token.Id, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !revoked {
+		return nil, nil, models.ErrInvalidRefreshToken
+	}
+
+	user, err := s.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = user.AccessError()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.issueTokenPair(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, pair, nil
+}
+
+// Logout revokes the refresh token. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, plain string) error {
+	return s.refreshTokens.RevokeByHash(ctx, utility.HashToken(plain), time.Now())
+}
+
+// Authenticate resolves a bearer access token to the active user it was
+// issued for.
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (*models.User, error) {
+	claims, err := utility.VerifyAccessToken([]byte(s.config.JWTSecret), accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, utility.ErrInvalidAccessToken
+	}
+
+	user, err := s.users.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	err = user.AccessError()
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// issueTokenPair signs a new access token for the user and stores a fresh
+// refresh token for it.
+func (s *AuthService) issueTokenPair(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	accessToken, err := utility.GenerateAccessToken([]byte(s.config.JWTSecret), s.config.AccessTokenTTL, user.Id, user.Email, string(user.UserType))
+	if err != nil {
+		return nil, err
+	}
+
+	plain, hash, err := utility.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.refreshTokens.Create(ctx, &models.RefreshToken{
+		UserID:    user.Id,
+		TokenHash: hash,
+		ExpireAt:  time.Now().Add(s.config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store refresh token: %w", err)
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: plain,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
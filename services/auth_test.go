@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/config"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/memory"
+	"testing"
+	"time"
+)
+
+const testMaxAttempts = 5
+
+func newTestAuth(t *testing.T) (*AuthService, repository.Repositories, *mailer.CaptureSender) {
+	t.Helper()
+
+	repos := memory.New()
+	mail := mailer.NewCaptureSender()
+	auth := NewAuthService(repos, mail, config.AuthConfig{
+		JWTSecret:               "test-secret",
+		AccessTokenTTL:          time.Minute,
+		RefreshTokenTTL:         time.Hour,
+		VerificationCodeTTL:     time.Minute,
+		VerificationMaxAttempts: testMaxAttempts,
+		VerificationLockout:     time.Hour,
+	})
+	return auth, repos, mail
+}
+
+func signup(t *testing.T, auth *AuthService, email string) *models.User {
+	t.Helper()
+
+	user, err := auth.Signup(context.Background(), &models.SignupRequest{
+		FullName:    "Jane Doe",
+		Email:       email,
+		Password:    "correct horse",
+		PhoneNumber: nextPhone(),
+		UserType:    models.WASTEWARRIOR,
+	})
+	if err != nil {
+		t.Fatalf("Signup: %v", err)
+	}
+	return user
+}
+
+// lastCode returns the code in the last email sent to the address with the
+// template.
+func lastCode(t *testing.T, mail *mailer.CaptureSender, email, template string) string {
+	t.Helper()
+
+	sent := mail.SentTo(email)
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].Template == template {
+			return sent[i].Data.(map[string]interface{})["Code"].(string)
+		}
+	}
+	t.Fatalf("no %s sent to %s", template, email)
+	return ""
+}
+
+// wrongCode returns a well formed code that differs from code.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestResendKeepsAttempts(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	signup(t, auth, "jane@example.com")
+
+	code := lastCode(t, mail, "jane@example.com", "user_token.html")
+	for i := 0; i < testMaxAttempts-1; i++ {
+		err := auth.VerifyCode(ctx, "jane@example.com", wrongCode(code))
+		if !errors.Is(err, models.ErrInvalidCode) {
+			t.Fatalf("guess %d: got %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+
+	err := auth.ResendVerificationCode(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("ResendVerificationCode: %v", err)
+	}
+	code = lastCode(t, mail, "jane@example.com", "user_token.html")
+
+	// The resent code only has the one attempt left over from the old one.
+	err = auth.VerifyCode(ctx, "jane@example.com", wrongCode(code))
+	if !errors.Is(err, models.ErrInvalidCode) {
+		t.Fatalf("last guess: got %v, want ErrInvalidCode", err)
+	}
+	err = auth.VerifyCode(ctx, "jane@example.com", code)
+	if !errors.Is(err, models.ErrVerificationLocked) {
+		t.Fatalf("right code after the last guess: got %v, want ErrVerificationLocked", err)
+	}
+
+	sent := len(mail.Sent())
+	err = auth.ResendVerificationCode(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("ResendVerificationCode while locked: %v", err)
+	}
+	if len(mail.Sent()) != sent {
+		t.Fatal("a code was sent while the verification was locked")
+	}
+}
+
+func TestReplaceStartsOverAfterLockout(t *testing.T) {
+	ctx := context.Background()
+	_, repos, _ := newTestAuth(t)
+
+	now := time.Now()
+	lockedUntil := now.Add(-time.Minute)
+	old := &models.UserToken{UserID: 1, Purpose: models.PurposeVerifyEmail, CodeHash: "old", ExpireAt: now.Add(time.Minute)}
+	err := repos.UserTokens.Replace(ctx, old, now)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	for i := 0; i < testMaxAttempts; i++ {
+		_, err := repos.UserTokens.RecordAttempt(ctx, old.Id, testMaxAttempts, lockedUntil)
+		if err != nil {
+			t.Fatalf("RecordAttempt: %v", err)
+		}
+	}
+
+	token := &models.UserToken{UserID: 1, Purpose: models.PurposeVerifyEmail, CodeHash: "new", ExpireAt: now.Add(time.Minute)}
+	err = repos.UserTokens.Replace(ctx, token, now)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	stored, err := repos.UserTokens.GetByUserID(ctx, 1, models.PurposeVerifyEmail)
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	if stored.Attempts != 0 || stored.LockedUntil != nil {
+		t.Fatalf("got %d attempts locked until %v, want a fresh code", stored.Attempts, stored.LockedUntil)
+	}
+}
+
+func TestSignupAndVerify(t *testing.T) {
+	ctx := context.Background()
+	auth, repos, mail := newTestAuth(t)
+	user := signup(t, auth, "jane@example.com")
+
+	sent := mail.SentTo("jane@example.com")
+	if len(sent) != 1 || sent[0].Template != "user_token.html" {
+		t.Fatalf("got %+v, want one user_token.html", sent)
+	}
+	data := sent[0].Data.(map[string]interface{})
+	if data["userName"] != "Jane Doe" || data["email"] != "jane@example.com" {
+		t.Fatalf("got data %v", data)
+	}
+
+	_, _, err := auth.Login(ctx, "jane@example.com", "correct horse")
+	if !errors.Is(err, models.ErrInactiveAccount) {
+		t.Fatalf("Login before verifying: got %v, want ErrInactiveAccount", err)
+	}
+
+	err = auth.VerifyCode(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "user_token.html"))
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	stored, err := repos.Users.GetByID(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Status != models.UserActive {
+		t.Fatalf("got status %s, want %s", stored.Status, models.UserActive)
+	}
+
+	// The code is used up.
+	err = auth.VerifyCode(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "user_token.html"))
+	if !errors.Is(err, models.ErrInvalidCode) {
+		t.Fatalf("VerifyCode twice: got %v, want ErrInvalidCode", err)
+	}
+
+	_, pair, err := auth.Login(ctx, "jane@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatalf("got token pair %+v", pair)
+	}
+}
+
+func TestResendSaysNothing(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	signup(t, auth, "jane@example.com")
+	err := auth.VerifyCode(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "user_token.html"))
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	mail.Reset()
+
+	for _, email := range []string{"jane@example.com", "nobody@example.com"} {
+		err := auth.ResendVerificationCode(ctx, email)
+		if err != nil {
+			t.Fatalf("ResendVerificationCode(%s): %v", email, err)
+		}
+	}
+	if sent := mail.Sent(); len(sent) != 0 {
+		t.Fatalf("got %d emails, want none for a verified and an unknown email", len(sent))
+	}
+}
+
+func TestCodesOnlyWorkForTheirPurpose(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	signup(t, auth, "jane@example.com")
+
+	err := auth.ForgotPassword(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+
+	// Asking for a reset leaves the verification code working.
+	verify := lastCode(t, mail, "jane@example.com", "user_token.html")
+	reset := lastCode(t, mail, "jane@example.com", "password_reset.html")
+	if verify != reset {
+		err = auth.VerifyCode(ctx, "jane@example.com", reset)
+		if !errors.Is(err, models.ErrInvalidCode) {
+			t.Fatalf("reset code used to verify: got %v, want ErrInvalidCode", err)
+		}
+	}
+	err = auth.VerifyCode(ctx, "jane@example.com", verify)
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	signup(t, auth, "jane@example.com")
+	err := auth.VerifyCode(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "user_token.html"))
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	_, session, err := auth.Login(ctx, "jane@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	err = auth.ForgotPassword(ctx, "nobody@example.com")
+	if err != nil {
+		t.Fatalf("ForgotPassword for an unknown email: %v", err)
+	}
+	if sent := mail.SentTo("nobody@example.com"); len(sent) != 0 {
+		t.Fatalf("got %d emails to an unknown address", len(sent))
+	}
+
+	err = auth.ForgotPassword(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	code := lastCode(t, mail, "jane@example.com", "password_reset.html")
+
+	err = auth.ResetPassword(ctx, "jane@example.com", wrongCode(code), "battery staple")
+	if !errors.Is(err, models.ErrInvalidCode) {
+		t.Fatalf("ResetPassword with a wrong code: got %v, want ErrInvalidCode", err)
+	}
+	err = auth.ResetPassword(ctx, "jane@example.com", code, "battery staple")
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	_, _, err = auth.Login(ctx, "jane@example.com", "correct horse")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("Login with the old password: got %v, want ErrInvalidCredentials", err)
+	}
+	_, _, err = auth.Login(ctx, "jane@example.com", "battery staple")
+	if err != nil {
+		t.Fatalf("Login with the new password: %v", err)
+	}
+
+	// Sessions started with the old password are over.
+	_, _, err = auth.Refresh(ctx, session.RefreshToken)
+	if !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh an old session: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	ctx := context.Background()
+	auth, repos, mail := newTestAuth(t)
+	user := signup(t, auth, "jane@example.com")
+	signup(t, auth, "taken@example.com")
+
+	err := auth.RequestEmailChange(ctx, user, "new@example.com", "wrong password")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("RequestEmailChange with a wrong password: got %v, want ErrInvalidCredentials", err)
+	}
+	err = auth.RequestEmailChange(ctx, user, "taken@example.com", "correct horse")
+	if !errors.Is(err, models.ErrDuplicateEmail) {
+		t.Fatalf("RequestEmailChange to a taken email: got %v, want ErrDuplicateEmail", err)
+	}
+
+	err = auth.RequestEmailChange(ctx, user, "new@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	code := lastCode(t, mail, "new@example.com", "email_change.html")
+
+	changed, err := auth.ConfirmEmailChange(ctx, user, code)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	if changed.Email != "new@example.com" {
+		t.Fatalf("got email %s, want new@example.com", changed.Email)
+	}
+	stored, err := repos.Users.GetByID(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Email != "new@example.com" {
+		t.Fatalf("stored email %s, want new@example.com", stored.Email)
+	}
+
+	// The old address is told about the change.
+	notices := mail.SentTo("jane@example.com")
+	if last := notices[len(notices)-1]; last.Template != "email_changed.html" {
+		t.Fatalf("got %s sent to the old address, want email_changed.html", last.Template)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	signup(t, auth, "jane@example.com")
+	err := auth.ForgotPassword(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+
+	code := lastCode(t, mail, "jane@example.com", "user_token.html")
+	for i := 0; i < testMaxAttempts; i++ {
+		err := auth.VerifyCode(ctx, "jane@example.com", wrongCode(code))
+		if !errors.Is(err, models.ErrInvalidCode) {
+			t.Fatalf("guess %d: got %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+	err = auth.VerifyCode(ctx, "jane@example.com", code)
+	if !errors.Is(err, models.ErrVerificationLocked) {
+		t.Fatalf("right code once locked: got %v, want ErrVerificationLocked", err)
+	}
+
+	// The lockout is per purpose, so the reset code still works.
+	err = auth.ResetPassword(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "password_reset.html"), "battery staple")
+	if err != nil {
+		t.Fatalf("ResetPassword while verification is locked: %v", err)
+	}
+}
+
+func TestLoginAndRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	auth, _, mail := newTestAuth(t)
+	user := signup(t, auth, "jane@example.com")
+	err := auth.VerifyCode(ctx, "jane@example.com", lastCode(t, mail, "jane@example.com", "user_token.html"))
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+
+	_, _, err = auth.Login(ctx, "jane@example.com", "wrong horse")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("Login with a wrong password: got %v, want ErrInvalidCredentials", err)
+	}
+	_, _, err = auth.Login(ctx, "nobody@example.com", "correct horse")
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("Login with an unknown email: got %v, want ErrInvalidCredentials", err)
+	}
+
+	_, first, err := auth.Login(ctx, "jane@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	authenticated, err := auth.Authenticate(ctx, first.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if authenticated.Id != user.Id {
+		t.Fatalf("Authenticate: got user %d, want %d", authenticated.Id, user.Id)
+	}
+
+	_, second, err := auth.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh returned the same refresh token")
+	}
+
+	// Reusing a rotated token means it leaked, so every session ends.
+	_, _, err = auth.Refresh(ctx, first.RefreshToken)
+	if !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh with a used token: got %v, want ErrInvalidRefreshToken", err)
+	}
+	_, _, err = auth.Refresh(ctx, second.RefreshToken)
+	if !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+
+	_, third, err := auth.Login(ctx, "jane@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	err = auth.Logout(ctx, third.RefreshToken)
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	_, _, err = auth.Refresh(ctx, third.RefreshToken)
+	if !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after logout: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
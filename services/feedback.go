@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"time"
+)
+
+// FeedbackService records the ratings warriors give partners after a pickup.
+type FeedbackService struct {
+	feedback     repository.FeedbackRepository
+	transactions repository.TransactionRepository
+}
+
+func NewFeedbackService(repos repository.Repositories) *FeedbackService {
+	return &FeedbackService{
+		feedback:     repos.Feedback,
+		transactions: repos.Transactions,
+	}
+}
+
+// Create records the warrior's feedback on a transaction they own. Feedback
+// is only accepted once per transaction and only after the bag was picked up.
+func (s *FeedbackService) Create(ctx context.Context, feedback *models.Feedback) error {
+	transaction, err := s.transactions.GetByID(ctx, feedback.TransactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.UserID != feedback.UserID {
+		return models.ErrTransactionNotFound
+	}
+	if transaction.PickedUpAt == nil {
+		return models.ErrNotPickedUp
+	}
+
+	feedback.PartnerID = transaction.PartnerID
+	return s.feedback.Create(ctx, feedback)
+}
+
+// Update changes the rating and comment. The partner's rating aggregate
+// follows the change.
+func (s *FeedbackService) Update(ctx context.Context, feedback *models.Feedback, rating int, comment string) error {
+	updated := *feedback
+	updated.Rating = rating
+	updated.Comment = comment
+
+	err := s.feedback.Update(ctx, &updated)
+	if err != nil {
+		return err
+	}
+
+	updated.DateUpdated = time.Now()
+	*feedback = updated
+	return nil
+}
+
+// Delete removes the feedback and its rating from the partner aggregate.
+func (s *FeedbackService) Delete(ctx context.Context, feedback *models.Feedback) error {
+	return s.feedback.Delete(ctx, feedback)
+}
+
+func (s *FeedbackService) GetByID(ctx context.Context, id int64) (*models.Feedback, error) {
+	return s.feedback.GetByID(ctx, id)
+}
+
+// GetForUserTransaction loads the feedback a user left on one of their
+// transactions.
+func (s *FeedbackService) GetForUserTransaction(ctx context.Context, userId, transactionId int64) (*models.Feedback, error) {
+	feedback, err := s.feedback.GetByTransaction(ctx, transactionId)
+	if err != nil {
+		return nil, err
+	}
+	if feedback.UserID != userId {
+		return nil, models.ErrFeedbackNotFound
+	}
+	return feedback, nil
+}
+
+// ListForPartner lists the feedback a partner received as the public sees it.
+func (s *FeedbackService) ListForPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.PublicFeedback, listing.Page, error) {
+	feedback, page, err := s.feedback.ListByPartner(ctx, partnerId, q)
+	if err != nil {
+		return nil, page, err
+	}
+
+	public := make([]*models.PublicFeedback, len(feedback))
+	for i, f := range feedback {
+		public[i] = f.Public()
+	}
+	return public, page, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"reflect"
+	"testing"
+)
+
+// pickedUp buys a bag of the partner for the warrior and marks it collected,
+// so that feedback can be given on it.
+func pickedUp(t *testing.T, purchases *PurchaseService, repos repository.Repositories, warrior *models.User, partner *models.Partner) *models.Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	bag := createBag(t, repos, partner.ID, 1)
+	transaction, err := purchases.Purchase(ctx, warrior.Id, bag.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("Purchase: %v", err)
+	}
+	transaction, err = purchases.MarkPickedUp(ctx, partner.ID, transaction.Id)
+	if err != nil {
+		t.Fatalf("MarkPickedUp: %v", err)
+	}
+	return transaction
+}
+
+func checkRating(t *testing.T, repos repository.Repositories, partnerId int64, count int, average float64, distribution map[int]int) {
+	t.Helper()
+
+	rating, err := repos.Feedback.PartnerRating(context.Background(), partnerId)
+	if err != nil {
+		t.Fatalf("PartnerRating: %v", err)
+	}
+	if rating.Count != count || rating.Average != average || !reflect.DeepEqual(rating.Distribution, distribution) {
+		t.Fatalf("got %d ratings averaging %v with %v, want %d averaging %v with %v",
+			rating.Count, rating.Average, rating.Distribution, count, average, distribution)
+	}
+}
+
+func TestFeedbackAggregates(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+	feedbacks := NewFeedbackService(repos)
+
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	other := createPartner(t, repos, "UTC", models.PartnerApproved)
+	jane := createWarrior(t, repos, "jane")
+	john := createWarrior(t, repos, "john")
+
+	checkRating(t, repos, partner.ID, 0, 0, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0})
+
+	five := &models.Feedback{Rating: 5, TransactionID: pickedUp(t, purchases, repos, jane, partner).Id, UserID: jane.Id}
+	err := feedbacks.Create(ctx, five)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	two := &models.Feedback{Rating: 2, TransactionID: pickedUp(t, purchases, repos, john, partner).Id, UserID: john.Id}
+	err = feedbacks.Create(ctx, two)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// Ratings of other partners stay out of the aggregate.
+	err = feedbacks.Create(ctx, &models.Feedback{Rating: 1, TransactionID: pickedUp(t, purchases, repos, jane, other).Id, UserID: jane.Id})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	checkRating(t, repos, partner.ID, 2, 3.5, map[int]int{1: 0, 2: 1, 3: 0, 4: 0, 5: 1})
+
+	err = feedbacks.Update(ctx, two, 4, "Better than it looked")
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	checkRating(t, repos, partner.ID, 2, 4.5, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1})
+
+	err = feedbacks.Delete(ctx, five)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	checkRating(t, repos, partner.ID, 1, 4, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 0})
+}
+
+func TestFeedbackRules(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+	feedbacks := NewFeedbackService(repos)
+
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	jane := createWarrior(t, repos, "jane")
+	john := createWarrior(t, repos, "john")
+
+	bag := createBag(t, repos, partner.ID, 1)
+	waiting, err := purchases.Purchase(ctx, john.Id, bag.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("Purchase: %v", err)
+	}
+	err = feedbacks.Create(ctx, &models.Feedback{Rating: 5, TransactionID: waiting.Id, UserID: john.Id})
+	if !errors.Is(err, models.ErrNotPickedUp) {
+		t.Fatalf("before pickup: got %v, want ErrNotPickedUp", err)
+	}
+
+	collected := pickedUp(t, purchases, repos, jane, partner)
+	err = feedbacks.Create(ctx, &models.Feedback{Rating: 5, TransactionID: collected.Id, UserID: john.Id})
+	if !errors.Is(err, models.ErrTransactionNotFound) {
+		t.Fatalf("on someone else's transaction: got %v, want ErrTransactionNotFound", err)
+	}
+
+	err = feedbacks.Create(ctx, &models.Feedback{Rating: 5, TransactionID: collected.Id, UserID: jane.Id})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	err = feedbacks.Create(ctx, &models.Feedback{Rating: 1, TransactionID: collected.Id, UserID: jane.Id})
+	if !errors.Is(err, models.ErrFeedbackExists) {
+		t.Fatalf("second feedback: got %v, want ErrFeedbackExists", err)
+	}
+	checkRating(t, repos, partner.ID, 1, 5, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 1})
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"time"
+)
+
+// MagicBagService manages the bags partners put on sale.
+type MagicBagService struct {
+	bags     repository.MagicBagRepository
+	products repository.ProductRepository
+	partners repository.PartnerRepository
+	users    repository.UserRepository
+	recorder Recorder
+}
+
+func NewMagicBagService(repos repository.Repositories, recorder Recorder) *MagicBagService {
+	return &MagicBagService{
+		bags:     repos.MagicBags,
+		products: repos.Products,
+		partners: repos.Partners,
+		users:    repos.Users,
+		recorder: recorder,
+	}
+}
+
+// Create puts a new bag on sale together with its contents.
+func (s *MagicBagService) Create(ctx context.Context, bag *models.MagicBag) error {
+	err := s.checkProductsExist(ctx, bag.PartnerID, bag.Items)
+	if err != nil {
+		return err
+	}
+
+	bag.Status = models.MagicBagActive
+	err = s.bags.Create(ctx, bag)
+	if err != nil {
+		return err
+	}
+
+	s.recorder.BagsListed(bag.Quantity)
+	return s.bags.Label(ctx, bag)
+}
+
+// Update saves the changes to the bag, which must already have them applied.
+// Its contents are replaced only when the changes list items, so a client can
+// update the price without resending every item.
+func (s *MagicBagService) Update(ctx context.Context, bag *models.MagicBag, changes *models.MagicBagChanges) error {
+	if bag.Status == models.MagicBagWithdrawn {
+		return models.ErrMagicBagWithdrawn
+	}
+
+	if changes.Items != nil {
+		err := s.checkProductsExist(ctx, bag.PartnerID, bag.Items)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.bags.Update(ctx, bag, changes)
+	if err != nil {
+		return err
+	}
+
+	return s.bags.Label(ctx, bag)
+}
+
+// Withdraw takes the bag off sale. Withdrawn bags are kept so existing
+// transactions still reference them.
+func (s *MagicBagService) Withdraw(ctx context.Context, bag *models.MagicBag) error {
+	return s.bags.Withdraw(ctx, bag)
+}
+
+// GetForPartner loads a bag with its contents only if it belongs to the
+// partner, so one partner can never read or change another partner's bag.
+func (s *MagicBagService) GetForPartner(ctx context.Context, partnerId, id int64) (*models.MagicBag, error) {
+	bag, err := s.bags.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bag.PartnerID != partnerId {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	bag.Items, err = s.bags.Items(ctx, bag.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bag, nil
+}
+
+func (s *MagicBagService) ListForPartner(ctx context.Context, partnerId int64, q listing.Query) ([]*models.MagicBag, listing.Page, error) {
+	bags, page, err := s.bags.ListByPartner(ctx, partnerId, q)
+	if err != nil {
+		return nil, page, err
+	}
+
+	for _, bag := range bags {
+		bag.Items, err = s.bags.Items(ctx, bag.ID)
+		if err != nil {
+			return nil, page, err
+		}
+	}
+
+	return bags, page, nil
+}
+
+// ListAvailable returns bags that waste warriors can buy: active, in stock,
+// not past their pickup window and offered by an approved partner whose
+// account is neither suspended nor deleted. Contents are not loaded since they stay
+// hidden until purchase; the bags only carry the allergens and dietary tags
+// of what is in them.
+func (s *MagicBagService) ListAvailable(ctx context.Context, q listing.Query) ([]*models.MagicBag, listing.Page, error) {
+	return s.bags.ListAvailable(ctx, q, time.Now())
+}
+
+// GetAvailable loads a bag for waste warriors. Like ListAvailable it only
+// finds active bags in stock that can still be picked up, from approved
+// partners that can still sell; any other bag is reported as not found.
+func (s *MagicBagService) GetAvailable(ctx context.Context, id int64) (*models.MagicBag, error) {
+	bag, err := s.bags.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bag.Status != models.MagicBagActive || bag.Quantity <= 0 || bag.PickupEnded(time.Now()) {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	partner, err := s.partners.GetByID(ctx, bag.PartnerID)
+	if err != nil {
+		if errors.Is(err, models.ErrPartnerNotFound) {
+			return nil, models.ErrMagicBagNotFound
+		}
+		return nil, err
+	}
+	if partner.Status != models.PartnerApproved {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	seller, err := s.users.GetByID(ctx, partner.UserID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, models.ErrMagicBagNotFound
+		}
+		return nil, err
+	}
+	if !seller.Sells() {
+		return nil, models.ErrMagicBagNotFound
+	}
+
+	return bag, nil
+}
+
+// checkProductsExist makes sure the items only list shared products and
+// those of the partner.
+func (s *MagicBagService) checkProductsExist(ctx context.Context, partnerId int64, items []models.MagicBagItem) error {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+
+	exist, err := s.products.Exist(ctx, partnerId, ids)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return models.ErrInvalidBagItems
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"testing"
+)
+
+func TestUpdateKeepsSoldStock(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+	bags := NewMagicBagService(repos, nopRecorder{})
+
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 3)
+
+	// The partner opens the bag for editing before a purchase comes in.
+	stale, err := bags.GetForPartner(ctx, partner.ID, bag.ID)
+	if err != nil {
+		t.Fatalf("GetForPartner: %v", err)
+	}
+	_, err = purchases.Purchase(ctx, createWarrior(t, repos, "jane").Id, bag.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("Purchase: %v", err)
+	}
+
+	title := "Pastry bag"
+	changes := &models.MagicBagChanges{Title: &title}
+	edited := *stale
+	changes.Apply(&edited)
+	err = bags.Update(ctx, &edited, changes)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	stored, err := repos.MagicBags.GetByID(ctx, bag.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Title != title || stored.Quantity != 2 || stored.BagPrice != bag.BagPrice {
+		t.Fatalf("got %q with %d left at %v, want %q with 2 left at %v", stored.Title, stored.Quantity, stored.BagPrice, title, bag.BagPrice)
+	}
+
+	quantity := 10
+	changes = &models.MagicBagChanges{Quantity: &quantity}
+	edited = *stored
+	changes.Apply(&edited)
+	err = bags.Update(ctx, &edited, changes)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	stored, err = repos.MagicBags.GetByID(ctx, bag.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Quantity != quantity || stored.Title != title {
+		t.Fatalf("got %q with %d left, want %q with %d left", stored.Title, stored.Quantity, title, quantity)
+	}
+}
+
+func TestUpdateWithdrawnBag(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+	bags := NewMagicBagService(repos, nopRecorder{})
+
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 3)
+	err := bags.Withdraw(ctx, bag)
+	if err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	quantity := 5
+	err = bags.Update(ctx, bag, &models.MagicBagChanges{Quantity: &quantity})
+	if !errors.Is(err, models.ErrMagicBagWithdrawn) {
+		t.Fatalf("got %v, want ErrMagicBagWithdrawn", err)
+	}
+}
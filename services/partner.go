@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"time"
+)
+
+// PartnerService manages partner business profiles and their review by
+// admins.
+type PartnerService struct {
+	partners repository.PartnerRepository
+	users    repository.UserRepository
+	feedback repository.FeedbackRepository
+	mail     mailer.Sender
+}
+
+func NewPartnerService(repos repository.Repositories, mail mailer.Sender) *PartnerService {
+	return &PartnerService{
+		partners: repos.Partners,
+		users:    repos.Users,
+		feedback: repos.Feedback,
+		mail:     mail,
+	}
+}
+
+// Create stores the business profile for the partner user. New profiles
+// always start pending until an admin reviews them.
+func (s *PartnerService) Create(ctx context.Context, partner *models.Partner) error {
+	partner.Status = models.PartnerPending
+	return s.partners.Create(ctx, partner)
+}
+
+// Update saves profile changes. A rejected profile, or an approved one whose
+// business number changed, goes back to pending for another review, and the
+// outcome of the last review is cleared.
+func (s *PartnerService) Update(ctx context.Context, partner, previous *models.Partner) error {
+	partner.Status = previous.Status
+	partner.RejectionReason = previous.RejectionReason
+	partner.ReviewedAt = previous.ReviewedAt
+	if previous.Status == models.PartnerRejected || (previous.Status == models.PartnerApproved && partner.BRNumber != previous.BRNumber) {
+		partner.Status = models.PartnerPending
+		partner.RejectionReason = ""
+		partner.ReviewedAt = nil
+	}
+
+	return s.partners.Update(ctx, partner)
+}
+
+func (s *PartnerService) GetByID(ctx context.Context, id int64) (*models.Partner, error) {
+	return s.partners.GetByID(ctx, id)
+}
+
+func (s *PartnerService) GetByUserID(ctx context.Context, userId int64) (*models.Partner, error) {
+	return s.partners.GetByUserID(ctx, userId)
+}
+
+func (s *PartnerService) List(ctx context.Context, q listing.Query) ([]*models.Partner, listing.Page, error) {
+	return s.partners.List(ctx, q)
+}
+
+// Review approves or rejects a pending profile and emails the outcome to the
+// partner user.
+func (s *PartnerService) Review(ctx context.Context, partner *models.Partner, status models.PartnerStatus, reason string) error {
+	if partner.Status != models.PartnerPending {
+		return models.ErrPartnerAlreadyReview
+	}
+
+	err := s.partners.Review(ctx, partner, status, reason, time.Now())
+	if err != nil {
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, partner.UserID)
+	if err != nil {
+		return err
+	}
+
+	templateFile := "partner_approved.html"
+	if status == models.PartnerRejected {
+		templateFile = "partner_rejected.html"
+	}
+
+	data := map[string]interface{}{
+		"userName":       user.FullName,
+		"businessNumber": partner.BRNumber,
+		"reason":         reason,
+	}
+	return s.mail.Send(user.Email, templateFile, data)
+}
+
+// PublicProfile loads the public profile of an approved partner.
+func (s *PartnerService) PublicProfile(ctx context.Context, id int64) (*models.PublicPartner, error) {
+	partner, err := s.partners.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if partner.Status != models.PartnerApproved {
+		return nil, models.ErrPartnerNotFound
+	}
+
+	user, err := s.users.GetByID(ctx, partner.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	rating, err := s.feedback.PartnerRating(ctx, partner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PublicPartner{
+		ID:      partner.ID,
+		Name:    user.FullName,
+		Logo:    partner.Logo,
+		Address: partner.Address,
+		Rating:  rating,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"github.com/horlathunbhosun/reducing-food-waste/mailer"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"testing"
+	"time"
+)
+
+func TestPartnerUpdateAfterReview(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+	partners := NewPartnerService(repos, mailer.NewCaptureSender())
+
+	rejected := createPartner(t, repos, "UTC", models.PartnerPending)
+	err := repos.Partners.Review(ctx, rejected, models.PartnerRejected, "Blurry logo", time.Now())
+	if err != nil {
+		t.Fatalf("Review: %v", err)
+	}
+	approved := createPartner(t, repos, "UTC", models.PartnerApproved)
+
+	tests := []struct {
+		name     string
+		previous *models.Partner
+		change   func(p *models.Partner)
+		status   models.PartnerStatus
+		reviewed bool
+	}{
+		{"rejected", rejected, func(p *models.Partner) { p.Logo = "logo.png" }, models.PartnerPending, false},
+		{"approved, same business", approved, func(p *models.Partner) { p.Address = "2 Market Street" }, models.PartnerApproved, true},
+		{"approved, new business", approved, func(p *models.Partner) { p.BRNumber = "BR-2" }, models.PartnerPending, false},
+	}
+
+	for _, tt := range tests {
+		previous, err := repos.Partners.GetByID(ctx, tt.previous.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		partner := *previous
+		tt.change(&partner)
+
+		err = partners.Update(ctx, &partner, previous)
+		if err != nil {
+			t.Fatalf("%s: Update: %v", tt.name, err)
+		}
+
+		stored, err := repos.Partners.GetByID(ctx, partner.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if stored.Status != tt.status {
+			t.Errorf("%s: got status %s, want %s", tt.name, stored.Status, tt.status)
+		}
+		if reviewed := stored.ReviewedAt != nil; reviewed != tt.reviewed {
+			t.Errorf("%s: got reviewed at %v, want reviewed %v", tt.name, stored.ReviewedAt, tt.reviewed)
+		}
+		if tt.status == models.PartnerPending && stored.RejectionReason != "" {
+			t.Errorf("%s: kept the rejection reason %q", tt.name, stored.RejectionReason)
+		}
+	}
+}
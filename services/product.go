@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+)
+
+// ProductService manages the product catalog. Admins look after the shared
+// products and partners after their own.
+type ProductService struct {
+	products repository.ProductRepository
+	partners repository.PartnerRepository
+}
+
+func NewProductService(repos repository.Repositories) *ProductService {
+	return &ProductService{
+		products: repos.Products,
+		partners: repos.Partners,
+	}
+}
+
+// Create adds the product to the shared catalog, or to the partner's
+// products when it has a PartnerID.
+func (s *ProductService) Create(ctx context.Context, product *models.Product) error {
+	product.SortLabels()
+	return s.products.Create(ctx, product)
+}
+
+func (s *ProductService) Update(ctx context.Context, product *models.Product) error {
+	product.SortLabels()
+	return s.products.Update(ctx, product)
+}
+
+// Delete removes the product unless a magic bag lists it, which would
+// otherwise change what the bag is labelled with.
+func (s *ProductService) Delete(ctx context.Context, product *models.Product) error {
+	return s.products.Delete(ctx, product.Id)
+}
+
+func (s *ProductService) Get(ctx context.Context, id int64) (*models.Product, error) {
+	return s.products.GetByID(ctx, id)
+}
+
+// GetPublic loads a product from the public catalog, which only has the
+// products of approved partners besides the shared ones.
+func (s *ProductService) GetPublic(ctx context.Context, id int64) (*models.Product, error) {
+	product, err := s.products.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.PartnerID == nil {
+		return product, nil
+	}
+
+	partner, err := s.partners.GetByID(ctx, *product.PartnerID)
+	if err != nil {
+		if errors.Is(err, models.ErrPartnerNotFound) {
+			return nil, models.ErrProductNotFound
+		}
+		return nil, err
+	}
+	if partner.Status != models.PartnerApproved {
+		return nil, models.ErrProductNotFound
+	}
+
+	return product, nil
+}
+
+// GetForPartner loads a product only if it belongs to the partner. Shared
+// products are reported as not found, since partners cannot change them.
+func (s *ProductService) GetForPartner(ctx context.Context, partnerId, id int64) (*models.Product, error) {
+	product, err := s.products.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.PartnerID == nil || *product.PartnerID != partnerId {
+		return nil, models.ErrProductNotFound
+	}
+	return product, nil
+}
+
+func (s *ProductService) List(ctx context.Context, q listing.Query) ([]*models.Product, listing.Page, error) {
+	return s.products.List(ctx, q)
+}
+
+// ListUsable returns the products the partner can put in their bags.
+func (s *ProductService) ListUsable(ctx context.Context, partnerId int64, q listing.Query) ([]*models.Product, listing.Page, error) {
+	return s.products.ListUsable(ctx, partnerId, q)
+}
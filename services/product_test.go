@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/validator"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func createProduct(t *testing.T, products *ProductService, product *models.Product) *models.Product {
+	t.Helper()
+
+	err := products.Create(context.Background(), product)
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return product
+}
+
+func TestProductFilters(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+	products := NewProductService(repos)
+
+	approved := createPartner(t, repos, "UTC", models.PartnerApproved)
+	pending := createPartner(t, repos, "UTC", models.PartnerPending)
+
+	bread := createProduct(t, products, &models.Product{Name: "Bread", Category: models.CategoryBakery,
+		Allergens: []models.Allergen{models.AllergenSesame, models.AllergenGluten}, DietaryTags: []models.DietaryTag{models.DietVegan}})
+	salad := createProduct(t, products, &models.Product{Name: "Salad", Category: models.CategoryProduce,
+		DietaryTags: []models.DietaryTag{models.DietGlutenFree, models.DietVegan}, PartnerID: &approved.ID})
+	cheese := createProduct(t, products, &models.Product{Name: "Cheese", Category: models.CategoryDairy,
+		Allergens: []models.Allergen{models.AllergenMilk}, DietaryTags: []models.DietaryTag{models.DietGlutenFree}})
+	// Products of partners that are not approved stay out of the catalog.
+	createProduct(t, products, &models.Product{Name: "Hidden", Category: models.CategoryOther, PartnerID: &pending.ID})
+
+	if want := []models.Allergen{models.AllergenGluten, models.AllergenSesame}; !reflect.DeepEqual(bread.Allergens, want) {
+		t.Fatalf("got allergens %v, want them in reporting order %v", bread.Allergens, want)
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"sort=id", []int64{bread.Id, salad.Id, cheese.Id}},
+		{"allergens[none]=gluten&sort=id", []int64{salad.Id, cheese.Id}},
+		{"allergens[none]=gluten,milk", []int64{salad.Id}},
+		{"dietary_tags[all]=vegan&sort=id", []int64{bread.Id, salad.Id}},
+		{"dietary_tags[all]=vegan,gluten_free", []int64{salad.Id}},
+		{"category=dairy", []int64{cheese.Id}},
+		{"shared=true&sort=id", []int64{bread.Id, cheese.Id}},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		v := validator.New()
+		q := listing.Parse(v, values, models.ProductListing)
+		if !v.Valid() {
+			t.Fatalf("%s: got errors %v", tt.query, v.Errors)
+		}
+
+		found, _, err := products.List(ctx, q)
+		if err != nil {
+			t.Fatalf("%s: List: %v", tt.query, err)
+		}
+		ids := make([]int64, len(found))
+		for i, product := range found {
+			ids[i] = product.Id
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	for _, query := range []string{"allergens[all]=milk", "allergens[none]=pollen", "dietary_tags=vegan"} {
+		values, _ := url.ParseQuery(query)
+		v := validator.New()
+		listing.Parse(v, values, models.ProductListing)
+		if v.Valid() {
+			t.Errorf("%s: was accepted", query)
+		}
+	}
+}
+
+func TestProductNotFound(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+	products := NewProductService(repos)
+	bags := NewMagicBagService(repos, nopRecorder{})
+
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	other := createPartner(t, repos, "UTC", models.PartnerApproved)
+	pending := createPartner(t, repos, "UTC", models.PartnerPending)
+	theirs := createProduct(t, products, &models.Product{Name: "Soup", Category: models.CategoryPreparedMeals, PartnerID: &other.ID})
+	hidden := createProduct(t, products, &models.Product{Name: "Cake", Category: models.CategoryBakery, PartnerID: &pending.ID})
+
+	for _, id := range []int64{hidden.Id, 999} {
+		_, err := products.GetPublic(ctx, id)
+		if !errors.Is(err, models.ErrProductNotFound) {
+			t.Errorf("GetPublic(%d): got %v, want ErrProductNotFound", id, err)
+		}
+	}
+	_, err := products.GetForPartner(ctx, partner.ID, theirs.Id)
+	if !errors.Is(err, models.ErrProductNotFound) {
+		t.Errorf("GetForPartner of another partner's product: got %v, want ErrProductNotFound", err)
+	}
+
+	bag := &models.MagicBag{Title: "Soup bag", BagPrice: 3, Quantity: 1, PartnerID: partner.ID,
+		Items: []models.MagicBagItem{{ProductID: theirs.Id, Quantity: 1}}}
+	err = bags.Create(ctx, bag)
+	if !errors.Is(err, models.ErrInvalidBagItems) {
+		t.Errorf("bag with another partner's product: got %v, want ErrInvalidBagItems", err)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"time"
+)
+
+// PurchaseService sells magic bags to waste warriors and follows their
+// payments through to pickup or refund.
+type PurchaseService struct {
+	transactions repository.TransactionRepository
+	bags         repository.MagicBagRepository
+	payments     payment.PaymentProvider
+	recorder     Recorder
+}
+
+func NewPurchaseService(repos repository.Repositories, payments payment.PaymentProvider, recorder Recorder) *PurchaseService {
+	return &PurchaseService{
+		transactions: repos.Transactions,
+		bags:         repos.MagicBags,
+		payments:     payments,
+		recorder:     recorder,
+	}
+}
+
+// Purchase buys one unit of a bag for a waste warrior. A warrior may only buy
+// one bag per partner per day, where the day is the partner's local day.
+// Card purchases are authorized with the payment provider once the order is
+// recorded; a declined card releases the bag again.
+func (s *PurchaseService) Purchase(ctx context.Context, userId, bagId int64, paymentType models.PaymentType, paymentSource string) (*models.Transaction, error) {
+	transaction, err := s.transactions.Reserve(ctx, userId, bagId, paymentType, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentType != models.CARD {
+		s.recorder.BagSold()
+		return transaction, nil
+	}
+
+	result, err := s.payments.Authorize(ctx, payment.AuthorizeRequest{
+		Amount:      transaction.Amount,
+		Source:      paymentSource,
+		Description: fmt.Sprintf("Magic bag %d", transaction.MagicBagID),
+	})
+	if result != nil {
+		transaction.PaymentReference = result.Reference
+	}
+	if err != nil {
+		failErr := s.updatePaymentStatus(ctx, transaction, payment.StatusFailed)
+		if failErr != nil {
+			return nil, failErr
+		}
+		if errors.Is(err, payment.ErrDeclined) {
+			return transaction, models.ErrPaymentDeclined
+		}
+		return nil, err
+	}
+
+	err = s.updatePaymentStatus(ctx, transaction, payment.StatusAuthorized)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recorder.BagSold()
+	return transaction, nil
+}
+
+// MarkPickedUp records that the warrior collected the bag from the partner.
+// This is when the payment is taken: card authorizations are captured and
+// cash payments are marked as received.
+func (s *PurchaseService) MarkPickedUp(ctx context.Context, partnerId, id int64) (*models.Transaction, error) {
+	t, err := s.transactions.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.PartnerID != partnerId {
+		return nil, models.ErrTransactionNotFound
+	}
+	if t.PickedUpAt != nil {
+		return nil, models.ErrAlreadyPickedUp
+	}
+
+	bag, err := s.bags.GetByID(ctx, t.MagicBagID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t.PaymentType == models.CARD && t.PaymentStatus == payment.StatusAuthorized:
+		_, err = s.payments.Capture(ctx, t.PaymentReference, t.Amount)
+		if err != nil {
+			return nil, err
+		}
+	case t.PaymentType == models.CASH && t.PaymentStatus == payment.StatusPending:
+	default:
+		return nil, models.ErrPaymentState
+	}
+
+	now := time.Now()
+	t.PickedUpAt = &now
+	err = s.updatePaymentStatus(ctx, t, payment.StatusCaptured)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recorder.BagPickedUp(bag.WeightKg)
+	return t, nil
+}
+
+// Refund gives the warrior their money back. Card payments are refunded
+// through the provider; cash refunds are settled by the partner.
+func (s *PurchaseService) Refund(ctx context.Context, id int64) (*models.Transaction, error) {
+	t, err := s.transactions.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanTransition(t.PaymentStatus, payment.StatusRefunded) {
+		return nil, models.ErrPaymentState
+	}
+
+	if t.PaymentType == models.CARD && t.PaymentReference != "" {
+		_, err = s.payments.Refund(ctx, t.PaymentReference, t.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.updatePaymentStatus(ctx, t, payment.StatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// HandleWebhook verifies a webhook from the payment provider and applies the
+// status change it carries.
+func (s *PurchaseService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.payments.VerifyWebhook(payload, signature)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return models.ErrInvalidSignature
+	}
+	if err != nil {
+		return err
+	}
+	return s.ApplyPaymentEvent(ctx, event)
+}
+
+// ApplyPaymentEvent updates a transaction from a verified provider webhook.
+// Events that would move the payment backwards are ignored, since webhooks
+// can arrive late or more than once.
+func (s *PurchaseService) ApplyPaymentEvent(ctx context.Context, event *payment.WebhookEvent) error {
+	t, err := s.transactions.GetByReference(ctx, event.Reference)
+	if err != nil {
+		return err
+	}
+
+	if t.PaymentStatus == event.Status || !models.CanTransition(t.PaymentStatus, event.Status) {
+		return nil
+	}
+
+	return s.updatePaymentStatus(ctx, t, event.Status)
+}
+
+// GetForUser loads one of the user's transactions together with the bag
+// contents, which are revealed once the bag has been bought.
+func (s *PurchaseService) GetForUser(ctx context.Context, userId, id int64) (*models.Transaction, error) {
+	t, err := s.transactions.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userId {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	t.MagicBag, err = s.bags.GetByID(ctx, t.MagicBagID)
+	if err != nil {
+		return nil, err
+	}
+	t.MagicBag.Items, err = s.bags.Items(ctx, t.MagicBagID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *PurchaseService) ListForUser(ctx context.Context, userId int64, q listing.Query) ([]*models.Transaction, listing.Page, error) {
+	return s.transactions.ListByUser(ctx, userId, q)
+}
+
+// updatePaymentStatus moves the payment to a new status. A purchase that
+// fails or is refunded before pickup puts the bag back on sale.
+func (s *PurchaseService) updatePaymentStatus(ctx context.Context, t *models.Transaction, status payment.Status) error {
+	from := t.PaymentStatus
+	if !models.CanTransition(from, status) {
+		return models.ErrPaymentState
+	}
+
+	t.PaymentStatus = status
+	err := s.transactions.UpdatePayment(ctx, t, from, t.ReleasesBag(status))
+	if err != nil {
+		t.PaymentStatus = from
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/horlathunbhosun/reducing-food-waste/listing"
+	"github.com/horlathunbhosun/reducing-food-waste/models"
+	"github.com/horlathunbhosun/reducing-food-waste/payment"
+	"github.com/horlathunbhosun/reducing-food-waste/repository"
+	"github.com/horlathunbhosun/reducing-food-waste/repository/memory"
+	"testing"
+	"time"
+)
+
+// nopRecorder ignores the business events.
+type nopRecorder struct{}
+
+func (nopRecorder) BagsListed(int)      {}
+func (nopRecorder) BagSold()            {}
+func (nopRecorder) BagPickedUp(float64) {}
+
+// phones hands out a different phone number to every user the tests create.
+var phones int
+
+func nextPhone() string {
+	phones++
+	return fmt.Sprintf("+23480%08d", phones)
+}
+
+func newTestPurchases(t *testing.T) (*PurchaseService, repository.Repositories) {
+	t.Helper()
+
+	repos := memory.New()
+	return NewPurchaseService(repos, payment.NewFakeProvider("test-secret"), nopRecorder{}), repos
+}
+
+// createWarrior stores an active waste warrior.
+func createWarrior(t *testing.T, repos repository.Repositories, name string) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		FullName:     name,
+		Email:        name + "@example.com",
+		PasswordHash: "hash",
+		PhoneNumber:  nextPhone(),
+		UserType:     models.WASTEWARRIOR,
+		Status:       models.UserActive,
+	}
+	err := repos.Users.Create(context.Background(), user)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createPartner stores a partner in the time zone with the given review
+// status.
+func createPartner(t *testing.T, repos repository.Repositories, timezone string, status models.PartnerStatus) *models.Partner {
+	t.Helper()
+	ctx := context.Background()
+
+	phone := nextPhone()
+	owner := &models.User{
+		FullName:    "Owner",
+		Email:       fmt.Sprintf("owner%d@example.com", phones),
+		PhoneNumber: phone,
+		UserType:    models.PARTNERS,
+		Status:      models.UserActive,
+	}
+	err := repos.Users.Create(ctx, owner)
+	if err != nil {
+		t.Fatalf("create partner user: %v", err)
+	}
+
+	partner := &models.Partner{
+		BRNumber: "BR-1",
+		Address:  "1 Market Street",
+		Timezone: timezone,
+		Status:   models.PartnerPending,
+		UserID:   owner.Id,
+	}
+	err = repos.Partners.Create(ctx, partner)
+	if err != nil {
+		t.Fatalf("create partner: %v", err)
+	}
+	if status != models.PartnerPending {
+		err = repos.Partners.Review(ctx, partner, status, "", time.Now())
+		if err != nil {
+			t.Fatalf("review partner: %v", err)
+		}
+	}
+	return partner
+}
+
+// createBag puts a bag of the partner on sale.
+func createBag(t *testing.T, repos repository.Repositories, partnerId int64, quantity int) *models.MagicBag {
+	t.Helper()
+
+	bag := &models.MagicBag{
+		Title:     "Bakery bag",
+		BagPrice:  4.5,
+		Quantity:  quantity,
+		Status:    models.MagicBagActive,
+		PartnerID: partnerId,
+	}
+	err := repos.MagicBags.Create(context.Background(), bag)
+	if err != nil {
+		t.Fatalf("create bag: %v", err)
+	}
+	return bag
+}
+
+func TestDailyPurchaseLimit(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+
+	warrior := createWarrior(t, repos, "jane")
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	other := createPartner(t, repos, "UTC", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 5)
+	sameShop := createBag(t, repos, partner.ID, 5)
+	otherShop := createBag(t, repos, other.ID, 5)
+
+	first, err := purchases.Purchase(ctx, warrior.Id, bag.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("first purchase: %v", err)
+	}
+
+	_, err = purchases.Purchase(ctx, warrior.Id, sameShop.ID, models.CASH, "")
+	if !errors.Is(err, models.ErrDailyPurchaseLimit) {
+		t.Fatalf("second bag of the partner: got %v, want ErrDailyPurchaseLimit", err)
+	}
+
+	_, err = purchases.Purchase(ctx, warrior.Id, otherShop.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("bag of another partner: %v", err)
+	}
+
+	// Someone else is not held to the warrior's limit.
+	_, err = purchases.Purchase(ctx, createWarrior(t, repos, "john").Id, sameShop.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("purchase by another warrior: %v", err)
+	}
+
+	// A refunded purchase no longer counts.
+	_, err = purchases.Refund(ctx, first.Id)
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	_, err = purchases.Purchase(ctx, warrior.Id, sameShop.ID, models.CASH, "")
+	if err != nil {
+		t.Fatalf("purchase after a refund: %v", err)
+	}
+}
+
+func TestDailyPurchaseLimitDeclinedCard(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+
+	warrior := createWarrior(t, repos, "jane")
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 5)
+
+	_, err := purchases.Purchase(ctx, warrior.Id, bag.ID, models.CARD, payment.DeclineSource)
+	if !errors.Is(err, models.ErrPaymentDeclined) {
+		t.Fatalf("declined card: got %v, want ErrPaymentDeclined", err)
+	}
+
+	_, err = purchases.Purchase(ctx, warrior.Id, bag.ID, models.CARD, "tok_visa")
+	if err != nil {
+		t.Fatalf("purchase after a declined card: %v", err)
+	}
+}
+
+func TestDailyPurchaseLimitUsesPartnerDay(t *testing.T) {
+	ctx := context.Background()
+	_, repos := newTestPurchases(t)
+
+	warrior := createWarrior(t, repos, "jane")
+	// Auckland is 13 hours ahead of UTC in January.
+	partner := createPartner(t, repos, "Pacific/Auckland", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 5)
+
+	lateEvening := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	_, err := repos.Transactions.Reserve(ctx, warrior.Id, bag.ID, models.CASH, lateEvening)
+	if err != nil {
+		t.Fatalf("Reserve at 23:00 local time: %v", err)
+	}
+
+	// Still 1 January in UTC, but already the next day for the partner.
+	afterMidnight := lateEvening.Add(2 * time.Hour)
+	transaction, err := repos.Transactions.Reserve(ctx, warrior.Id, bag.ID, models.CASH, afterMidnight)
+	if err != nil {
+		t.Fatalf("Reserve after local midnight: %v", err)
+	}
+	if transaction.PurchaseDay != "2024-01-02" {
+		t.Fatalf("got purchase day %s, want 2024-01-02", transaction.PurchaseDay)
+	}
+
+	_, err = repos.Transactions.Reserve(ctx, warrior.Id, bag.ID, models.CASH, afterMidnight.Add(time.Hour))
+	if !errors.Is(err, models.ErrDailyPurchaseLimit) {
+		t.Fatalf("second purchase on the local day: got %v, want ErrDailyPurchaseLimit", err)
+	}
+}
+
+func TestPurchaseUnavailableBags(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+	bags := NewMagicBagService(repos, nopRecorder{})
+
+	warrior := createWarrior(t, repos, "jane")
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+
+	withdrawn := createBag(t, repos, partner.ID, 5)
+	err := bags.Withdraw(ctx, withdrawn)
+	if err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+	_, err = purchases.Purchase(ctx, warrior.Id, withdrawn.ID, models.CASH, "")
+	if !errors.Is(err, models.ErrMagicBagWithdrawn) {
+		t.Fatalf("withdrawn bag: got %v, want ErrMagicBagWithdrawn", err)
+	}
+
+	ended := createBag(t, repos, partner.ID, 5)
+	start, end := time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)
+	changes := &models.MagicBagChanges{PickupStart: &start, PickupEnd: &end}
+	changes.Apply(ended)
+	err = bags.Update(ctx, ended, changes)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	_, err = purchases.Purchase(ctx, warrior.Id, ended.ID, models.CASH, "")
+	if !errors.Is(err, models.ErrPickupEnded) {
+		t.Fatalf("bag past its pickup window: got %v, want ErrPickupEnded", err)
+	}
+	_, err = bags.GetAvailable(ctx, ended.ID)
+	if !errors.Is(err, models.ErrMagicBagNotFound) {
+		t.Fatalf("GetAvailable past the pickup window: got %v, want ErrMagicBagNotFound", err)
+	}
+
+	open := createBag(t, repos, partner.ID, 5)
+	listed, _, err := bags.ListAvailable(ctx, listing.Query{Limit: listing.DefaultLimit, Sort: []listing.Sort{{Field: "id"}}})
+	if err != nil {
+		t.Fatalf("ListAvailable: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != open.ID {
+		t.Fatalf("got %d bags listed, want only the open one", len(listed))
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	ctx := context.Background()
+	purchases, repos := newTestPurchases(t)
+	warrior := createWarrior(t, repos, "jane")
+	partner := createPartner(t, repos, "UTC", models.PartnerApproved)
+	bag := createBag(t, repos, partner.ID, 5)
+
+	transaction, err := purchases.Purchase(ctx, warrior.Id, bag.ID, models.CARD, "tok_visa")
+	if err != nil {
+		t.Fatalf("Purchase: %v", err)
+	}
+
+	payload := []byte(fmt.Sprintf(`{"reference":%q,"status":"captured"}`, transaction.PaymentReference))
+	signer := payment.NewFakeProvider("test-secret")
+
+	err = purchases.HandleWebhook(ctx, payload, payment.NewFakeProvider("other-secret").Sign(payload))
+	if !errors.Is(err, models.ErrInvalidSignature) {
+		t.Fatalf("HandleWebhook with a bad signature: got %v, want ErrInvalidSignature", err)
+	}
+	stored, err := repos.Transactions.GetByID(ctx, transaction.Id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.PaymentStatus != payment.StatusAuthorized {
+		t.Fatalf("got status %s after a rejected webhook, want %s", stored.PaymentStatus, payment.StatusAuthorized)
+	}
+
+	err = purchases.HandleWebhook(ctx, payload, signer.Sign(payload))
+	if err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+	stored, err = repos.Transactions.GetByID(ctx, transaction.Id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.PaymentStatus != payment.StatusCaptured {
+		t.Fatalf("got status %s, want %s", stored.PaymentStatus, payment.StatusCaptured)
+	}
+}
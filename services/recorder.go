@@ -0,0 +1,12 @@
+package services
+
+// Recorder is told about the business events that are reported as metrics,
+// so the impact of the API can be followed next to its health.
+type Recorder interface {
+	// BagsListed is called with the number of units in a new listing.
+	BagsListed(count int)
+	BagSold()
+	// BagPickedUp is called with the partner's estimate of the food in the
+	// bag, which is zero when the partner did not give one.
+	BagPickedUp(weightKg float64)
+}